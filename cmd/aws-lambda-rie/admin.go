@@ -0,0 +1,66 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	adminPathPrefix   = "/_rie"
+	adminTokenEnvKey  = "AWS_LAMBDA_RIE_ADMIN_TOKEN"
+	adminTokenHeader  = "X-Rie-Admin-Token"
+	authorizationType = "Bearer "
+)
+
+// adminOnly protects the emulator control endpoints under /_rie. The endpoints
+// are disabled unless AWS_LAMBDA_RIE_ADMIN_TOKEN is set, in which case callers must
+// present the token in the X-Rie-Admin-Token header or as a Bearer token.
+func adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv(adminTokenEnvKey)
+		if token == "" {
+			writeJSONError(w, http.StatusForbidden, "AdminAPIDisabled", "Set "+adminTokenEnvKey+" to enable the emulator admin API")
+			return
+		}
+
+		presented := r.Header.Get(adminTokenHeader)
+		if auth := r.Header.Get("Authorization"); presented == "" && strings.HasPrefix(auth, authorizationType) {
+			presented = strings.TrimPrefix(auth, authorizationType)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			log.Warnf("Rejected admin request %s %s: invalid admin token", r.Method, r.URL.Path)
+			writeJSONError(w, http.StatusUnauthorized, "InvalidAdminToken", "Missing or invalid admin token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminNotFound answers requests to undefined paths under /_rie with the control endpoints there are,
+// a mistyped admin call must not invoke the function
+func adminNotFound(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints := []string{}
+		chi.Walk(routes, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			endpoints = append(endpoints, method+" "+adminPathPrefix+route)
+			return nil
+		})
+		sort.Strings(endpoints)
+		writeJSON(w, http.StatusNotFound, struct {
+			ErrorType    string   `json:"errorType"`
+			ErrorMessage string   `json:"errorMessage"`
+			Endpoints    []string `json:"endpoints"`
+		}{"AdminEndpointNotFound", "No emulator control endpoint " + r.Method + " " + r.URL.Path, endpoints})
+	}
+}
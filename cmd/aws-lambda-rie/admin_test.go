@@ -0,0 +1,39 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminNotFoundListsEndpoints(t *testing.T) {
+	invoked := false
+	r := chi.NewRouter()
+	r.Route(adminPathPrefix, func(rie chi.Router) {
+		rie.NotFound(adminNotFound(rie))
+		rie.Get("/ui", UIHandler)
+		rie.Group(func(admin chi.Router) {
+			admin.Use(adminOnly)
+			admin.Post("/pause", func(w http.ResponseWriter, r *http.Request) {})
+			admin.Post("/history/{id}/replay", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+	r.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) { invoked = true })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/_rie/puase", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{
+		"errorType": "AdminEndpointNotFound",
+		"errorMessage": "No emulator control endpoint POST /_rie/puase",
+		"endpoints": ["GET /_rie/ui", "POST /_rie/history/{id}/replay", "POST /_rie/pause"]
+	}`, w.Body.String())
+	assert.False(t, invoked, "a mistyped admin call does not invoke the function")
+}
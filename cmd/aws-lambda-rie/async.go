@@ -0,0 +1,169 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	invocationTypeHeader          = "X-Amz-Invocation-Type"
+	invocationTypeRequestResponse = "RequestResponse"
+	invocationTypeEvent           = "Event"
+	invocationTypeDryRun          = "DryRun"
+
+	asyncQueueMaxEnvKey  = "AWS_LAMBDA_RIE_ASYNC_QUEUE_MAX"
+	defaultAsyncQueueMax = 1000
+	asyncQueueFullReason = "AsyncQueueFull"
+
+	// how long a queued Event invoke waits before retrying while a synchronous invoke holds the sandbox
+	eventBusyInitialBackoff = 10 * time.Millisecond
+	eventBusyMaxBackoff     = time.Second
+)
+
+// eventInvokes runs the Event invokes in the background, one at a time like the single sandbox does
+var eventInvokes = &asyncQueue{}
+
+type asyncQueue struct {
+	once sync.Once
+	jobs chan func()
+}
+
+type asyncQueueStats struct {
+	Length   int `json:"length"`
+	Capacity int `json:"capacity"`
+}
+
+// asyncQueueMax is the number of Event invokes that can wait for the sandbox, AWS_LAMBDA_RIE_ASYNC_QUEUE_MAX
+func asyncQueueMax() int {
+	configured := GetenvWithDefault(asyncQueueMaxEnvKey, strconv.Itoa(defaultAsyncQueueMax))
+	max, err := strconv.Atoi(configured)
+	if err != nil || max < 1 {
+		log.Warnf("Invalid %s %q, using %d", asyncQueueMaxEnvKey, configured, defaultAsyncQueueMax)
+		return defaultAsyncQueueMax
+	}
+	return max
+}
+
+// start sizes the queue on first use and runs its jobs
+func (q *asyncQueue) start() {
+	q.once.Do(func() {
+		q.jobs = make(chan func(), asyncQueueMax())
+		go func() {
+			for job := range q.jobs {
+				job()
+			}
+		}()
+	})
+}
+
+// enqueue schedules job and returns false when the queue is full
+func (q *asyncQueue) enqueue(job func()) bool {
+	q.start()
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// stats counts the invokes waiting in the queue, not the one running
+func (q *asyncQueue) stats() asyncQueueStats {
+	q.start()
+	return asyncQueueStats{Length: len(q.jobs), Capacity: cap(q.jobs)}
+}
+
+// handleInvocationType answers the invocation types that do not wait for the function, as the Invoke API does:
+// Event invokes are queued and answered with 202, DryRun ones with 204 without running the function.
+// It returns false for RequestResponse invokes, which InvokeHandler runs.
+func handleInvocationType(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs interop.Bootstrap) bool {
+	switch invocationType := r.Header.Get(invocationTypeHeader); invocationType {
+	case "", invocationTypeRequestResponse:
+		return false
+	case invocationTypeDryRun:
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	case invocationTypeEvent:
+		enqueueEventInvoke(w, r, sandbox, bs)
+		return true
+	default:
+		writeJSONError(w, http.StatusBadRequest, "InvalidParameterValueException",
+			"Invalid "+invocationTypeHeader+" "+invocationType+", it must be RequestResponse, Event or DryRun")
+		return true
+	}
+}
+
+func enqueueEventInvoke(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs interop.Bootstrap) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		log.Warnf("Rejected Event invoke, the payload exceeds the %d bytes limit", maxBytesErr.Limit)
+		writeInvokeAPIError(w, http.StatusRequestEntityTooLarge, payloadTooLargeMessage())
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to read invoke body: %s", err)
+		writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
+		return
+	}
+
+	// the client is answered before the invoke runs, so it must not depend on the request's context
+	background := r.Clone(context.Background())
+	background.Header.Del(invocationTypeHeader)
+
+	queued := eventInvokes.enqueue(func() {
+		resp := runEventInvoke(background, body, sandbox, bs)
+		log.Infof("Event invoke %s finished with status %d: %s", resp.header.Get(requestIDHeader), resp.statusCode, redactPayload(resp.body.Bytes()))
+	})
+	if !queued {
+		writeAsyncQueueFull(w, eventInvokes.stats())
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runEventInvoke retries, with an exponential backoff, while the sandbox is busy with a synchronous invoke: the
+// client was answered with 202 already, so a throttled Event invoke would be lost otherwise
+func runEventInvoke(r *http.Request, body []byte, sandbox Sandbox, bs interop.Bootstrap) *bufferedResponse {
+	backoff := eventBusyInitialBackoff
+	for {
+		req := r.Clone(context.Background())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		resp := newBufferedResponse()
+		InvokeHandler(resp, req, sandbox, bs)
+		if resp.header.Get("Retry-After") == "" {
+			return resp
+		}
+
+		log.Debugf("The sandbox is busy, retrying the Event invoke in %s", backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > eventBusyMaxBackoff {
+			backoff = eventBusyMaxBackoff
+		}
+	}
+}
+
+// writeAsyncQueueFull throttles an Event invoke like writeSandboxBusy does a synchronous one, with the
+// same status and Retry-After header, so that a backlog of Event invokes cannot grow without bounds
+func writeAsyncQueueFull(w http.ResponseWriter, queue asyncQueueStats) {
+	log.Warnf("Rejecting Event invoke, the queue is full with %d invokes (%s)", queue.Length, asyncQueueMaxEnvKey)
+	w.Header().Set("Retry-After", busyRetryAfterSecond)
+	writeJSON(w, busyStatus(), busyResponse{
+		ErrorType:    busyErrorType,
+		ErrorMessage: "Rate Exceeded.",
+		Reason:       asyncQueueFullReason,
+		Pool:         poolStats{Size: 1, Busy: 1, QueueLength: queue.Length, QueueCapacity: queue.Capacity},
+	})
+}
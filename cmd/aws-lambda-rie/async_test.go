@@ -0,0 +1,182 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+)
+
+func TestInvokeHandlerEventInvocation(t *testing.T) {
+	received := make(chan string, 1)
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		payload, _ := ioutil.ReadAll(i.Payload)
+		received <- string(payload)
+		return nil
+	}}
+	req := newInvokeRequest(`{"async": true}`)
+	req.Header.Set(invocationTypeHeader, invocationTypeEvent)
+
+	w := invoke(t, sandbox, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, `{"async": true}`, payload)
+	case <-time.After(time.Second):
+		require.Fail(t, "the Event invoke did not run")
+	}
+}
+
+func TestEventInvokeWaitsForSyncInvoke(t *testing.T) {
+	queue := eventInvokes
+	eventInvokes = &asyncQueue{}
+	t.Cleanup(func() { eventInvokes = queue })
+
+	var busy atomic.Bool
+	syncStarted := make(chan struct{})
+	release := make(chan struct{})
+	received := make(chan string, 1)
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		payload, _ := ioutil.ReadAll(i.Payload)
+		if !busy.CompareAndSwap(false, true) {
+			return rapidcore.ErrAlreadyReserved
+		}
+		defer busy.Store(false)
+		if string(payload) == `"sync"` {
+			close(syncStarted)
+			<-release
+			return respondWith(`"ok"`)(w, i)
+		}
+		received <- string(payload)
+		return nil
+	}}
+
+	syncDone := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		InvokeHandler(w, newInvokeRequest(`"sync"`), sandbox, NewSimpleBootstrap([]string{}, ""))
+		syncDone <- w.Code
+	}()
+	<-syncStarted
+
+	req := newInvokeRequest(`"event"`)
+	req.Header.Set(invocationTypeHeader, invocationTypeEvent)
+	assert.Equal(t, http.StatusAccepted, invoke(t, sandbox, req).Code)
+	select {
+	case <-received:
+		require.Fail(t, "the Event invoke ran while the sandbox was busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-syncDone)
+	select {
+	case payload := <-received:
+		assert.Equal(t, `"event"`, payload, "the Event invoke runs once the sandbox is released")
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "the Event invoke was dropped")
+	}
+}
+
+func TestEventInvokePayloadLimit(t *testing.T) {
+	queue := eventInvokes
+	eventInvokes = &asyncQueue{}
+	t.Cleanup(func() { eventInvokes = queue })
+	maxPayloadBytes = 8
+	t.Cleanup(func() { maxPayloadBytes = syncPayloadLimitBytes })
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	req := newInvokeRequest(`"abcdefg"`)
+	req.Header.Set(invocationTypeHeader, invocationTypeEvent)
+	w := invoke(t, sandbox, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "RequestTooLargeException")
+	assert.Equal(t, asyncQueueStats{Length: 0, Capacity: defaultAsyncQueueMax}, eventInvokes.stats(), "the invoke is not queued")
+}
+
+func TestInvokeHandlerDryRun(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	req := newInvokeRequest("{}")
+	req.Header.Set(invocationTypeHeader, invocationTypeDryRun)
+
+	w := invoke(t, sandbox, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Zero(t, sandbox.initCalls)
+}
+
+func TestInvokeHandlerRejectsUnknownInvocationType(t *testing.T) {
+	req := newInvokeRequest("{}")
+	req.Header.Set(invocationTypeHeader, "Later")
+
+	w := invoke(t, &mockSandbox{invoke: respondWith(`"ok"`)}, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "InvalidParameterValueException")
+}
+
+func TestInvokeHandlerRejectsEventInvokesWhenTheQueueIsFull(t *testing.T) {
+	t.Setenv(asyncQueueMaxEnvKey, "1")
+	queue := eventInvokes
+	eventInvokes = &asyncQueue{}
+	t.Cleanup(func() { eventInvokes = queue })
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}}
+	eventInvoke := func() *httptest.ResponseRecorder {
+		req := newInvokeRequest("{}")
+		req.Header.Set(invocationTypeHeader, invocationTypeEvent)
+		return invoke(t, sandbox, req)
+	}
+
+	assert.Equal(t, http.StatusAccepted, eventInvoke().Code)
+	<-started
+	assert.Equal(t, http.StatusAccepted, eventInvoke().Code, "one invoke can wait while the first runs")
+	assert.Equal(t, asyncQueueStats{Length: 1, Capacity: 1}, eventInvokes.stats())
+
+	rejected := eventInvoke()
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, busyRetryAfterSecond, rejected.Header().Get("Retry-After"))
+	var body busyResponse
+	require.NoError(t, json.Unmarshal(rejected.Body.Bytes(), &body))
+	assert.Equal(t, asyncQueueFullReason, body.Reason)
+	assert.Equal(t, poolStats{Size: 1, Busy: 1, QueueLength: 1, QueueCapacity: 1}, body.Pool)
+
+	t.Setenv(busyStatusEnvKey, "503")
+	assert.Equal(t, http.StatusServiceUnavailable, eventInvoke().Code)
+
+	close(release)
+	<-started
+	assert.Eventually(t, func() bool { return eventInvokes.stats().Length == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestAsyncQueueMax(t *testing.T) {
+	assert.Equal(t, defaultAsyncQueueMax, asyncQueueMax())
+
+	t.Setenv(asyncQueueMaxEnvKey, "5")
+	assert.Equal(t, 5, asyncQueueMax())
+
+	for _, invalid := range []string{"0", "-1", "many"} {
+		t.Setenv(asyncQueueMaxEnvKey, invalid)
+		assert.Equal(t, defaultAsyncQueueMax, asyncQueueMax(), invalid)
+	}
+}
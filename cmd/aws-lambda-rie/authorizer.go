@@ -0,0 +1,41 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	authorizerContextEnvKey = "AWS_LAMBDA_RIE_AUTHORIZER_CONTEXT"
+	authorizerContextHeader = "X-Rie-Authorizer-Context"
+)
+
+// authorizerContext returns the requestContext.authorizer of the API Gateway events, as if an authorizer
+// had accepted the request, e.g. {"jwt": {"claims": {"sub": "123"}}} or {"lambda": {"tenant": "a"}}.
+// The X-Rie-Authorizer-Context header overrides AWS_LAMBDA_RIE_AUTHORIZER_CONTEXT, nil means no authorizer.
+func authorizerContext(r *http.Request) (map[string]interface{}, error) {
+	if value := r.Header.Get(authorizerContextHeader); value != "" {
+		var authorizer map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &authorizer); err != nil {
+			return nil, fmt.Errorf("invalid %s header, it must be a JSON object: %s", authorizerContextHeader, err)
+		}
+		return authorizer, nil
+	}
+
+	value := GetenvWithDefault(authorizerContextEnvKey, "")
+	if value == "" {
+		return nil, nil
+	}
+	var authorizer map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &authorizer); err != nil {
+		log.Warnf("Invalid %s %q, it must be a JSON object, using no authorizer", authorizerContextEnvKey, value)
+		return nil, nil
+	}
+	return authorizer, nil
+}
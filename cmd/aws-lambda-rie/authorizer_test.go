@@ -0,0 +1,53 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectInvokeAuthorizerContext(t *testing.T) {
+	t.Setenv(authorizerContextEnvKey, `{"jwt": {"claims": {"sub": "user-1"}, "scopes": ["read"]}}`)
+
+	t.Run("from the environment", func(t *testing.T) {
+		var event AwsFunctionRequestPayload
+		directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, httptest.NewRequest("GET", "/hello", nil))
+
+		claims := event.RequestContext.Authorizer["jwt"].(map[string]interface{})["claims"]
+		assert.Equal(t, map[string]interface{}{"sub": "user-1"}, claims)
+	})
+
+	t.Run("overridden per request", func(t *testing.T) {
+		var event APIGatewayProxyRequest
+		req := newAPIGatewayRestRequest("GET", "/hello", "")
+		req.Header.Set(authorizerContextHeader, `{"lambda": {"tenant": "a"}}`)
+
+		directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+
+		assert.Equal(t, map[string]interface{}{"lambda": map[string]interface{}{"tenant": "a"}}, event.RequestContext.Authorizer)
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.Header.Set(authorizerContextHeader, `not json`)
+
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(`"ok"`)}, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), authorizerContextHeader)
+	})
+}
+
+func TestDirectInvokeWithoutAuthorizerContext(t *testing.T) {
+	t.Setenv(authorizerContextEnvKey, `[1, 2]`)
+	var event AwsFunctionRequestPayload
+
+	directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, httptest.NewRequest("GET", "/hello", nil))
+
+	assert.Nil(t, event.RequestContext.Authorizer)
+}
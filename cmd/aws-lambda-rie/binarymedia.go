@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const binaryMediaTypesEnvKey = "AWS_LAMBDA_RIE_BINARY_MEDIA_TYPES"
+
+// textMediaTypes are the content types Function URLs pass as is unless AWS_LAMBDA_RIE_BINARY_MEDIA_TYPES is set,
+// the patterns are matched with path.Match so that * stops at the / of the media type
+var textMediaTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/xml",
+	"application/*+xml",
+	"application/javascript",
+	"application/x-www-form-urlencoded",
+	"application/graphql",
+	"application/yaml",
+}
+
+// isBinaryBody tells whether the body of the request is base64 encoded in the event. Bodies of the binary media
+// types, of requests without a content type and bodies that are not valid UTF-8 are, the others are passed as is.
+// With AWS_LAMBDA_RIE_BINARY_MEDIA_TYPES set, like API Gateway's binary media types, the media types of the list
+// are binary and all the others text.
+func isBinaryBody(r *http.Request, body []byte) bool {
+	if !utf8.Valid(body) {
+		return true
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		log.Debugf("Invalid Content-Type %q, base64 encoding the body: %s", contentType, err)
+		return true
+	}
+
+	if configured := GetenvWithDefault(binaryMediaTypesEnvKey, ""); configured != "" {
+		return matchesMediaType(strings.Split(configured, ","), mediaType)
+	}
+	return !matchesMediaType(textMediaTypes, mediaType)
+}
+
+func matchesMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), mediaType); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctionURLBase64ByContentType(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	sandbox := &mockSandbox{invoke: captureEvent(&event)}
+	post := func(contentType string, body string) AwsFunctionRequestPayload {
+		event = AwsFunctionRequestPayload{}
+		req := httptest.NewRequest("POST", "/hello", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		directInvoke(t, sandbox, req)
+		return event
+	}
+	assertText := func(contentType string, body string) {
+		t.Helper()
+		event := post(contentType, body)
+		assert.False(t, event.IsBase64Encoded, contentType)
+		assert.Equal(t, body, event.Body, contentType)
+	}
+	assertBinary := func(contentType string, body string) {
+		t.Helper()
+		event := post(contentType, body)
+		assert.True(t, event.IsBase64Encoded, contentType)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(body)), event.Body, contentType)
+	}
+
+	assertText("application/json", `{"a":1}`)
+	assertText("Application/JSON; charset=utf-8", `{"a":1}`)
+	assertText("application/vnd.api+json", `{"a":1}`)
+	assertText("text/plain", "hello")
+	assertText("application/x-www-form-urlencoded", "a=1&b=2")
+	assertBinary("image/png", "png")
+	assertBinary("application/octet-stream", "bytes")
+	assertBinary("", "no content type")
+	assertBinary("text/plain", "\xff\xfe")
+
+	t.Setenv(binaryMediaTypesEnvKey, "image/*, application/pdf")
+	assertBinary("image/png", "png")
+	assertBinary("application/pdf", "pdf")
+	assertText("application/octet-stream", "bytes")
+	assertText("application/json", `{"a":1}`)
+}
@@ -0,0 +1,59 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	busyStatusEnvKey     = "AWS_LAMBDA_RIE_BUSY_STATUS"
+	defaultBusyStatus    = http.StatusTooManyRequests
+	busyRetryAfterSecond = "1"
+	busyErrorType        = "TooManyRequestsException"
+	busyReason           = "ReservedFunctionConcurrentInvocationLimitExceeded"
+)
+
+// poolStats describes the capacity that was exhausted. The emulator has a single sandbox and only
+// queues Event invokes, the fields leave room for more.
+type poolStats struct {
+	Size          int `json:"size"`
+	Busy          int `json:"busy"`
+	QueueLength   int `json:"queueLength"`
+	QueueCapacity int `json:"queueCapacity"`
+}
+
+type busyResponse struct {
+	ErrorType    string    `json:"errorType"`
+	ErrorMessage string    `json:"errorMessage"`
+	Reason       string    `json:"Reason"`
+	Pool         poolStats `json:"pool"`
+}
+
+// writeSandboxBusy answers an invoke that arrived while every sandbox was busy, like Lambda's
+// throttling does: 429 (or AWS_LAMBDA_RIE_BUSY_STATUS) with a Retry-After header
+func writeSandboxBusy(w http.ResponseWriter) {
+	queue := eventInvokes.stats()
+	w.Header().Set("Retry-After", busyRetryAfterSecond)
+	writeJSON(w, busyStatus(), busyResponse{
+		ErrorType:    busyErrorType,
+		ErrorMessage: "Rate Exceeded.",
+		Reason:       busyReason,
+		Pool:         poolStats{Size: 1, Busy: 1, QueueLength: queue.Length, QueueCapacity: queue.Capacity},
+	})
+}
+
+func busyStatus() int {
+	value := GetenvWithDefault(busyStatusEnvKey, strconv.Itoa(defaultBusyStatus))
+	switch value {
+	case strconv.Itoa(http.StatusTooManyRequests), strconv.Itoa(http.StatusServiceUnavailable):
+		status, _ := strconv.Atoi(value)
+		return status
+	}
+	log.Warnf("Invalid %s %q, it must be 429 or 503, using %d", busyStatusEnvKey, value, defaultBusyStatus)
+	return defaultBusyStatus
+}
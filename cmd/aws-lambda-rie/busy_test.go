@@ -0,0 +1,39 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvokeWhileBusyIsThrottled(t *testing.T) {
+	queue := eventInvokes
+	eventInvokes = &asyncQueue{}
+	t.Cleanup(func() { eventInvokes = queue })
+	busy := func(w http.ResponseWriter, i *interop.Invoke) error { return rapidcore.ErrAlreadyReserved }
+	sandbox := newTrackedSandbox("0", &mockSandbox{invoke: busy})
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	var resp busyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, busyErrorType, resp.ErrorType)
+	assert.Equal(t, poolStats{Size: 1, Busy: 1, QueueLength: 0, QueueCapacity: defaultAsyncQueueMax}, resp.Pool)
+	assert.Equal(t, 0, sandbox.snapshot().Invokes, "throttled invokes are not counted")
+
+	t.Setenv(busyStatusEnvKey, "503")
+	assert.Equal(t, http.StatusServiceUnavailable, invoke(t, sandbox, newInvokeRequest("{}")).Code)
+
+	t.Setenv(busyStatusEnvKey, "500")
+	assert.Equal(t, http.StatusTooManyRequests, invoke(t, sandbox, newInvokeRequest("{}")).Code)
+}
@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	chaosEnvKey = "AWS_LAMBDA_RIE_CHAOS"
+
+	chaosFaultError = "error"
+	chaosFaultDelay = "delay"
+)
+
+func chaosEnabled() bool {
+	return GetenvWithDefault(chaosEnvKey, "false") == "true"
+}
+
+// runtimeAPIFault is the fault injected in the Runtime API until Until
+type runtimeAPIFault struct {
+	Fault      string    `json:"fault"`
+	DurationMs int64     `json:"durationMs"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	DelayMs    int64     `json:"delayMs,omitempty"`
+	Until      time.Time `json:"until"`
+}
+
+// runtimeAPIChaos makes the internal Runtime API fail or slow down for a while, to test
+// how runtimes and extensions cope with transient Runtime API failures. Only meant for testing.
+type runtimeAPIChaos struct {
+	mutex sync.Mutex
+	fault *runtimeAPIFault
+}
+
+func newRuntimeAPIChaos() *runtimeAPIChaos {
+	return &runtimeAPIChaos{}
+}
+
+func (c *runtimeAPIChaos) active() *runtimeAPIFault {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.fault == nil || time.Now().After(c.fault.Until) {
+		c.fault = nil
+		return nil
+	}
+	fault := *c.fault
+	return &fault
+}
+
+func (c *runtimeAPIChaos) set(fault *runtimeAPIFault) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fault = fault
+}
+
+// middleware is installed on the Runtime API, see SandboxBuilder.SetRuntimeAPIMiddleware
+func (c *runtimeAPIChaos) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := c.active()
+		if fault == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch fault.Fault {
+		case chaosFaultDelay:
+			log.Debugf("Chaos: delaying %s %s by %d ms", r.Method, r.URL.Path, fault.DelayMs)
+			time.Sleep(time.Duration(fault.DelayMs) * time.Millisecond)
+			next.ServeHTTP(w, r)
+		case chaosFaultError:
+			log.Debugf("Chaos: failing %s %s with %d", r.Method, r.URL.Path, fault.StatusCode)
+			writeJSONError(w, fault.StatusCode, "Chaos.InjectedFault", "Fault injected by the emulator")
+		}
+	})
+}
+
+// RuntimeAPIChaosHandler sets, or with DELETE clears, the fault injected in the Runtime API
+func RuntimeAPIChaosHandler(w http.ResponseWriter, r *http.Request, chaos *runtimeAPIChaos) {
+	if chaos == nil {
+		writeJSONError(w, http.StatusForbidden, "ChaosDisabled", "Set "+chaosEnvKey+"=true to enable fault injection")
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		chaos.set(nil)
+		log.Warn("Chaos: Runtime API fault cleared")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var fault runtimeAPIFault
+	if err := json.NewDecoder(r.Body).Decode(&fault); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), "Invalid fault: "+err.Error())
+		return
+	}
+
+	switch {
+	case fault.Fault != chaosFaultError && fault.Fault != chaosFaultDelay:
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), `fault must be "error" or "delay"`)
+		return
+	case fault.DurationMs <= 0:
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), "durationMs must be positive")
+		return
+	case fault.Fault == chaosFaultDelay && fault.DelayMs <= 0:
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), "delayMs must be positive")
+		return
+	}
+
+	if fault.Fault == chaosFaultError && fault.StatusCode == 0 {
+		fault.StatusCode = http.StatusInternalServerError
+	}
+	if fault.Fault == chaosFaultError && (fault.StatusCode < 400 || fault.StatusCode > 599) {
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), "statusCode must be a 4xx or 5xx status")
+		return
+	}
+
+	fault.Until = time.Now().Add(time.Duration(fault.DurationMs) * time.Millisecond).UTC()
+	chaos.set(&fault)
+	log.Warnf("Chaos: injecting %s faults in the Runtime API for %d ms", fault.Fault, fault.DurationMs)
+	writeJSON(w, http.StatusOK, fault)
+}
@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeAPIChaosDisabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	RuntimeAPIChaosHandler(w, httptest.NewRequest("POST", "/_rie/chaos/runtime-api", strings.NewReader(`{"fault": "error", "durationMs": 1000}`)), nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRuntimeAPIChaosInjectsErrors(t *testing.T) {
+	chaos := newRuntimeAPIChaos()
+	runtimeAPI := chaos.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	next := func() int {
+		w := httptest.NewRecorder()
+		runtimeAPI.ServeHTTP(w, httptest.NewRequest("GET", "/2018-06-01/runtime/invocation/next", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, next())
+
+	w := httptest.NewRecorder()
+	RuntimeAPIChaosHandler(w, httptest.NewRequest("POST", "/_rie/chaos/runtime-api", strings.NewReader(`{"fault": "error", "durationMs": 50, "statusCode": 503}`)), chaos)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, next())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, next(), "the fault expires after durationMs")
+
+	RuntimeAPIChaosHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/_rie/chaos/runtime-api", strings.NewReader(`{"fault": "error", "durationMs": 60000}`)), chaos)
+	assert.Equal(t, http.StatusInternalServerError, next())
+	RuntimeAPIChaosHandler(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/_rie/chaos/runtime-api", nil), chaos)
+	assert.Equal(t, http.StatusOK, next())
+}
+
+func TestRuntimeAPIChaosRejectsInvalidFaults(t *testing.T) {
+	for _, body := range []string{
+		`{"fault": "partition", "durationMs": 1000}`,
+		`{"fault": "error"}`,
+		`{"fault": "delay", "durationMs": 1000}`,
+		`{"fault": "error", "durationMs": 1000, "statusCode": 200}`,
+	} {
+		w := httptest.NewRecorder()
+		RuntimeAPIChaosHandler(w, httptest.NewRequest("POST", "/_rie/chaos/runtime-api", strings.NewReader(body)), newRuntimeAPIChaos())
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"go.amzn.com/lambda/rapi/model"
+)
+
+const (
+	clientContextHeader         = "X-Amz-Client-Context"
+	invalidClientContextMessage = "Client context must be a valid Base64-encoded JSON object."
+
+	cognitoIdentityHeader         = "X-Amz-Cognito-Identity"
+	invalidCognitoIdentityMessage = "Cognito identity must be a JSON object with cognitoIdentityId and cognitoIdentityPoolId."
+)
+
+// invokeClientContext decodes the base64 X-Amz-Client-Context header the SDKs send, the runtime gets
+// the JSON it holds in Lambda-Runtime-Client-Context like in Lambda.
+// It is not valid when the header is not base64 or does not hold a JSON object.
+func invokeClientContext(r *http.Request) (string, bool) {
+	header := r.Header.Get(clientContextHeader)
+	if header == "" {
+		return "", true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return "", false
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(decoded, &object) != nil || object == nil {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// invokeCognitoIdentity reads the identity of an X-Amz-Cognito-Identity header, e.g.
+// {"cognitoIdentityId": "us-east-1:...", "cognitoIdentityPoolId": "us-east-1:..."}, that the runtime gets in
+// Lambda-Runtime-Cognito-Identity like the identity of an invoke from the mobile SDKs.
+// It is not valid when the header does not hold a JSON object.
+func invokeCognitoIdentity(r *http.Request) (model.CognitoIdentity, bool) {
+	header := r.Header.Get(cognitoIdentityHeader)
+	if header == "" {
+		return model.CognitoIdentity{}, true
+	}
+	var identity *model.CognitoIdentity
+	if json.Unmarshal([]byte(header), &identity) != nil || identity == nil {
+		return model.CognitoIdentity{}, false
+	}
+	return *identity, true
+}
@@ -0,0 +1,124 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/core/statejson"
+)
+
+const (
+	coldStartProbabilityEnvKey = "AWS_LAMBDA_RIE_COLDSTART_PROBABILITY"
+	seedEnvKey                 = "AWS_LAMBDA_RIE_SEED"
+	resetPerInvokeEnvKey       = "AWS_LAMBDA_RIE_RESET"
+
+	coldStartResetReason    = "coldstart"
+	coldStartResetTimeoutMs = 2000
+)
+
+type resetFunc func(reason string, timeoutMs int64) (*statejson.ResetDescription, error)
+
+// resetPerInvoke is set by --reset-per-invoke
+var resetPerInvoke bool
+
+// resetsEveryInvoke tells whether every invoke starts cold, with --reset-per-invoke or AWS_LAMBDA_RIE_RESET=1
+func resetsEveryInvoke() bool {
+	if resetPerInvoke {
+		return true
+	}
+	configured := GetenvWithDefault(resetPerInvokeEnvKey, "")
+	if configured == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(configured)
+	if err != nil {
+		log.Warnf("Invalid %s %q, the sandbox is not reset between invokes", resetPerInvokeEnvKey, configured)
+		return false
+	}
+	return enabled
+}
+
+// coldStarts resets the sandbox before an invoke with the configured probability, so that
+// the invoke starts cold like it does when Lambda recycles an execution environment
+type coldStarts struct {
+	probability float64
+	mutex       sync.Mutex
+	random      *rand.Rand
+	reset       resetFunc
+	sandbox     *trackedSandbox
+}
+
+// newColdStartsFromEnv returns nil unless AWS_LAMBDA_RIE_COLDSTART_PROBABILITY is set or every invoke is reset,
+// which is a probability of 1
+func newColdStartsFromEnv(reset resetFunc, sandbox *trackedSandbox) *coldStarts {
+	configured := GetenvWithDefault(coldStartProbabilityEnvKey, "")
+	everyInvoke := resetsEveryInvoke()
+	if configured == "" && !everyInvoke {
+		return nil
+	}
+	if neverReset() {
+		log.Warnf("Cold starts are not injected, %s keeps the sandbox warm", neverResetEnvKey)
+		return nil
+	}
+	if everyInvoke {
+		log.Info("Resetting the sandbox before every invoke, each one starts cold")
+		return &coldStarts{probability: 1, random: rand.New(rand.NewSource(time.Now().UnixNano())), reset: reset, sandbox: sandbox}
+	}
+
+	probability, err := strconv.ParseFloat(configured, 64)
+	if err != nil || probability < 0 || probability > 1 {
+		log.Warnf("Invalid %s %q, it must be between 0 and 1, cold starts are not injected", coldStartProbabilityEnvKey, configured)
+		return nil
+	}
+
+	seed := time.Now().UnixNano()
+	if configuredSeed := GetenvWithDefault(seedEnvKey, ""); configuredSeed != "" {
+		if seed, err = strconv.ParseInt(configuredSeed, 10, 64); err != nil {
+			log.Warnf("Invalid %s %q, using a random seed", seedEnvKey, configuredSeed)
+			seed = time.Now().UnixNano()
+		}
+	}
+	log.Infof("Injecting cold starts with probability %g, %s=%d", probability, seedEnvKey, seed)
+
+	return &coldStarts{probability: probability, random: rand.New(rand.NewSource(seed)), reset: reset, sandbox: sandbox}
+}
+
+func (c *coldStarts) roll() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.random.Float64() < c.probability
+}
+
+func (c *coldStarts) resetOnRoll() {
+	if !c.roll() {
+		return
+	}
+	log.Info("Resetting the sandbox to force a cold start")
+	if _, err := c.reset(coldStartResetReason, coldStartResetTimeoutMs); err != nil {
+		log.Warnf("Reset before cold start failed: %s", err)
+	}
+	initDone = false
+}
+
+// middleware is installed on the invoke routes. A sandbox that was not initialized yet
+// is going to start cold anyway, and one that is serving another invoke must not be reset
+// under it, so neither is reset nor counted against the probability.
+func (c *coldStarts) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c != nil {
+			initMutex.Lock()
+			if initDone && !c.sandbox.whileIdle(c.resetOnRoll) {
+				log.Debug("The sandbox is serving another invoke, it is not reset for a cold start")
+			}
+			initMutex.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+)
+
+func TestColdStartsDisabledByDefault(t *testing.T) {
+	assert.Nil(t, newColdStartsFromEnv(nil, nil))
+	t.Setenv(coldStartProbabilityEnvKey, "1.5")
+	assert.Nil(t, newColdStartsFromEnv(nil, nil))
+	t.Setenv(coldStartProbabilityEnvKey, "1")
+	t.Setenv(neverResetEnvKey, "true")
+	assert.Nil(t, newColdStartsFromEnv(nil, nil), "cold starts are not injected in a sandbox kept warm")
+}
+
+func TestColdStartsResetInitializedSandbox(t *testing.T) {
+	t.Setenv(coldStartProbabilityEnvKey, "1")
+	resets := 0
+	coldStarts := newColdStartsFromEnv(func(reason string, timeoutMs int64) (*statejson.ResetDescription, error) {
+		assert.Equal(t, coldStartResetReason, reason)
+		resets++
+		return &statejson.ResetDescription{}, nil
+	}, newTrackedSandbox("0", &mockSandbox{}))
+	handler := coldStarts.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(func() { initDone = false })
+
+	initDone = false
+	handler.ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	assert.Equal(t, 0, resets, "a sandbox that was never initialized is not reset")
+
+	initDone = true
+	handler.ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	assert.Equal(t, 1, resets)
+	assert.False(t, initDone, "the next invoke initializes the sandbox again")
+}
+
+// assertNotResetDuringInvoke sends an invoke while another one holds the sandbox, which is rejected
+// without resetting the sandbox, then checks that the next invoke is reset once the sandbox is idle
+func assertNotResetDuringInvoke(t *testing.T) {
+	t.Helper()
+	started, release := make(chan struct{}), make(chan struct{})
+	var running atomic.Bool
+	sandbox := newTrackedSandbox("0", &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		if running.Swap(true) {
+			return rapidcore.ErrAlreadyReserved
+		}
+		defer running.Store(false)
+		started <- struct{}{}
+		<-release
+		w.Write([]byte(`"ok"`))
+		return nil
+	}})
+	resets := 0
+	coldStarts := newColdStartsFromEnv(func(reason string, timeoutMs int64) (*statejson.ResetDescription, error) {
+		resets++
+		return &statejson.ResetDescription{}, nil
+	}, sandbox)
+	handler := coldStarts.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+	initDone = true
+	t.Cleanup(func() { initDone = false })
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(first, newInvokeRequest("{}"))
+		close(done)
+	}()
+	<-started
+	assert.Equal(t, 1, resets, "the first invoke is reset")
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+	assert.Equal(t, 1, resets, "the sandbox serving the first invoke is not reset")
+
+	close(release)
+	<-done
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, `"ok"`, first.Body.String())
+
+	done = make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+		close(done)
+	}()
+	<-started
+	assert.Equal(t, 2, resets, "the next invoke is reset once the sandbox is idle")
+	<-done
+}
+
+func TestColdStartsDoNotResetSandboxServingAnotherInvoke(t *testing.T) {
+	t.Setenv(coldStartProbabilityEnvKey, "1")
+	assertNotResetDuringInvoke(t)
+}
+
+func TestResetPerInvoke(t *testing.T) {
+	t.Cleanup(func() { resetPerInvoke = false })
+	for _, value := range []string{"", "0", "false", "yes"} {
+		t.Setenv(resetPerInvokeEnvKey, value)
+		assert.False(t, resetsEveryInvoke(), value)
+	}
+	t.Setenv(resetPerInvokeEnvKey, "1")
+	assert.True(t, resetsEveryInvoke())
+	t.Setenv(resetPerInvokeEnvKey, "")
+	resetPerInvoke = true
+	assert.True(t, resetsEveryInvoke(), "--reset-per-invoke")
+
+	resets := 0
+	coldStarts := newColdStartsFromEnv(func(reason string, timeoutMs int64) (*statejson.ResetDescription, error) {
+		resets++
+		return &statejson.ResetDescription{}, nil
+	}, newTrackedSandbox("0", &mockSandbox{}))
+	handler := coldStarts.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { initDone = true }))
+	t.Cleanup(func() { initDone = false })
+
+	initDone = false
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	}
+	assert.Equal(t, 4, resets, "every invoke after the first one starts cold")
+
+	t.Setenv(neverResetEnvKey, "true")
+	assert.Nil(t, newColdStartsFromEnv(nil, nil))
+}
+
+func TestResetPerInvokeDoesNotResetSandboxServingAnotherInvoke(t *testing.T) {
+	resetPerInvoke = true
+	t.Cleanup(func() { resetPerInvoke = false })
+	assertNotResetDuringInvoke(t)
+}
+
+func TestColdStartsAreReproducibleWithSeed(t *testing.T) {
+	t.Setenv(coldStartProbabilityEnvKey, "0.3")
+	t.Setenv(seedEnvKey, "42")
+
+	rolls := func() []bool {
+		coldStarts := newColdStartsFromEnv(nil, nil)
+		results := make([]bool, 50)
+		for i := range results {
+			results[i] = coldStarts.roll()
+		}
+		return results
+	}
+
+	first := rolls()
+	assert.Equal(t, first, rolls())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func TestNilColdStartsMiddlewarePassesThrough(t *testing.T) {
+	var coldStarts *coldStarts
+	called := false
+	coldStarts.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })).ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	assert.True(t, called)
+}
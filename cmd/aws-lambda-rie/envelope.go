@@ -0,0 +1,173 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	responseEnvelopeEnvKey = "AWS_LAMBDA_RIE_RESPONSE_ENVELOPE"
+
+	responseEnvelopeAuto   = "auto"
+	responseEnvelopeAlways = "always"
+	responseEnvelopeNever  = "never"
+
+	internalServerError = "Internal Server Error"
+)
+
+// errInvalidUTF8Body is reported to the client, unlike other invalid responses, since the fix is in the handler
+var errInvalidUTF8Body = errors.New("body is not valid UTF-8, binary bodies must be base64 encoded with isBase64Encoded set")
+
+// responseEnvelopeMode tells whether function responses on the direct path are interpreted
+// as a {"statusCode", "headers", "cookies", "body", "isBase64Encoded"} envelope:
+// auto when the response has a statusCode, always (502 otherwise) or never
+func responseEnvelopeMode() string {
+	mode := GetenvWithDefault(responseEnvelopeEnvKey, responseEnvelopeAuto)
+	switch mode {
+	case responseEnvelopeAuto, responseEnvelopeAlways, responseEnvelopeNever:
+		return mode
+	}
+	log.Warnf("Invalid %s %q, using %s", responseEnvelopeEnvKey, mode, responseEnvelopeAuto)
+	return responseEnvelopeAuto
+}
+
+// see https://docs.aws.amazon.com/lambda/latest/dg/urls-invocation.html#urls-response-payload
+type responseEnvelope struct {
+	StatusCode      *int              `json:"statusCode"`
+	Headers         map[string]string `json:"headers"`
+	Cookies         []string          `json:"cookies"`
+	Body            *string           `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// parseResponseEnvelope returns the envelope found in body, or nil if body is not one
+func parseResponseEnvelope(body []byte) (*responseEnvelope, error) {
+	var envelope responseEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.StatusCode == nil {
+		return nil, nil
+	}
+
+	if *envelope.StatusCode < 100 || *envelope.StatusCode > 599 {
+		return nil, fmt.Errorf("invalid statusCode %d", *envelope.StatusCode)
+	}
+	// json.Unmarshal silently replaces invalid UTF-8, so it is checked on the raw response
+	if !envelope.IsBase64Encoded && !utf8.Valid(body) {
+		return nil, errInvalidUTF8Body
+	}
+	return &envelope, nil
+}
+
+func (e *responseEnvelope) write(w http.ResponseWriter) error {
+	var body []byte
+	if e.Body != nil {
+		body = []byte(*e.Body)
+		if e.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(*e.Body)
+			if err != nil {
+				return fmt.Errorf("invalid base64 body: %s", err)
+			}
+			body = decoded
+		}
+	}
+
+	for k, v := range e.Headers {
+		w.Header().Set(k, v)
+	}
+	// each cookie gets a Set-Cookie header of its own, cookies cannot be joined with commas like other values
+	for _, cookie := range e.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	// a Content-Length that does not match the body would break the response to the client
+	if declared := w.Header().Get("Content-Length"); declared != "" && declared != strconv.Itoa(len(body)) {
+		log.Warnf("Function response declares Content-Length %s but its body has %d bytes, sending %d", declared, len(body), len(body))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(*e.StatusCode)
+	w.Write(body)
+	return nil
+}
+
+// bufferedResponse holds the response of InvokeHandler so that DirectInvokeHandler
+// can interpret it before anything is sent to the client
+type bufferedResponse struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}, statusCode: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(statusCode int) {
+	b.statusCode = statusCode
+}
+
+// copyTo sends the buffered response unchanged
+func (b *bufferedResponse) copyTo(w http.ResponseWriter) {
+	for k, vs := range b.header {
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(b.statusCode)
+	w.Write(b.body.Bytes())
+}
+
+// writeDirectResponse sends the function response to the client of the direct path,
+// unwrapping the response envelope according to AWS_LAMBDA_RIE_RESPONSE_ENVELOPE
+func writeDirectResponse(w http.ResponseWriter, resp *bufferedResponse, format *eventFormat) {
+	mode := responseEnvelopeMode()
+	isFunctionResponse := resp.statusCode == http.StatusOK && resp.header.Get(functionErrorHeader) == ""
+	if !format.responseEnvelope || mode == responseEnvelopeNever || !isFunctionResponse {
+		resp.copyTo(w)
+		return
+	}
+
+	envelope, err := parseResponseEnvelope(resp.body.Bytes())
+	if err == nil && envelope == nil && mode == responseEnvelopeAlways {
+		err = fmt.Errorf("response is not an envelope with a statusCode")
+	}
+	if err != nil {
+		log.Errorf("Invalid function response: %s", err)
+		message := internalServerError
+		if errors.Is(err, errInvalidUTF8Body) {
+			message += ": the function response " + err.Error()
+		}
+		format.writeError(w, http.StatusBadGateway, message)
+		return
+	}
+
+	// the request ID and tracing headers set by InvokeHandler are kept
+	for k, vs := range resp.header {
+		w.Header()[k] = vs
+	}
+
+	if envelope == nil {
+		w.WriteHeader(resp.statusCode)
+		w.Write(resp.body.Bytes())
+		return
+	}
+
+	if err := envelope.write(w); err != nil {
+		log.Errorf("Invalid function response: %s", err)
+		format.writeError(w, http.StatusBadGateway, internalServerError)
+	}
+}
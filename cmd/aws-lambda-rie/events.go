@@ -0,0 +1,332 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventFormatEnvKey     = "AWS_LAMBDA_RIE_EVENT_FORMAT"
+	eventFormatHeader     = "X-Rie-Event-Format"
+	allowedMethodsEnvKey  = "AWS_LAMBDA_RIE_ALLOWED_METHODS"
+	defaultAcceptEnvKey   = "AWS_LAMBDA_RIE_DEFAULT_ACCEPT"
+	maxRequestBytesEnvKey = "AWS_LAMBDA_RIE_MAX_REQUEST_BYTES"
+	allowQueryBodyEnvKey  = "AWS_LAMBDA_RIE_ALLOW_QUERY_BODY"
+	queryBodyParam        = "body"
+	requestTooLargeError  = "Request Entity Too Large"
+
+	// payload caps of the triggers, see https://docs.aws.amazon.com/lambda/latest/dg/gettingstarted-limits.html
+	syncPayloadLimitBytes  = 6 * 1024 * 1024
+	asyncPayloadLimitBytes = 256 * 1024
+	defaultEventFormat     = "function-url"
+	methodNotAllowedError  = "Method Not Allowed"
+	routingErrorType       = "RoutingError"
+)
+
+// errRouting fails the events that need the request path when the route did not capture it
+var errRouting = errors.New("routing failed")
+
+// maxPayloadBytes is the largest payload InvokeHandler accepts and the largest event DirectInvokeHandler builds,
+// set by main from --max-payload-bytes
+var maxPayloadBytes int64 = syncPayloadLimitBytes
+
+// payloadTooLargeMessage is the message of the Invoke API for payloads larger than maxPayloadBytes
+func payloadTooLargeMessage() string {
+	return fmt.Sprintf("Request must be smaller than %d bytes for the InvokeFunction operation", maxPayloadBytes)
+}
+
+// maxResponseBytes is the largest function response InvokeHandler returns, set by main from --max-response-bytes
+var maxResponseBytes int64 = syncPayloadLimitBytes
+
+// eventFormat describes how DirectInvokeHandler maps an HTTP request to the
+// event of a given trigger, and how it rejects requests the trigger would not accept
+type eventFormat struct {
+	// methods accepted by the trigger, unless overridden by AWS_LAMBDA_RIE_ALLOWED_METHODS, see formatEnv
+	allowedMethods []string
+	buildEvent     func(r *http.Request, body []byte) (interface{}, error)
+	// set instead of buildEvent by stream triggers, which split the records of the body in batches
+	buildStreamEvent func(r *http.Request, records [][]byte) (interface{}, error)
+	writeError       func(w http.ResponseWriter, statusCode int, message string)
+	// whether the trigger interprets structured responses, see writeDirectResponse
+	responseEnvelope bool
+	// largest request body the trigger accepts, unless overridden by AWS_LAMBDA_RIE_MAX_REQUEST_BYTES, see formatEnv
+	maxRequestBytes int64
+}
+
+var eventFormats = map[string]*eventFormat{
+	"function-url": {
+		allowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		buildEvent:     buildFunctionURLEvent,
+		writeError:     writeFunctionURLError,
+
+		responseEnvelope: true,
+		maxRequestBytes:  syncPayloadLimitBytes,
+	},
+	"apigw-rest": {
+		allowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		buildEvent:     buildAPIGatewayRestEvent,
+		writeError:     writeAPIGatewayError,
+
+		responseEnvelope: true,
+		maxRequestBytes:  syncPayloadLimitBytes,
+	},
+	"sns": {
+		allowedMethods:  []string{"POST", "PUT"},
+		buildEvent:      buildSNSEvent,
+		writeError:      writeInvokeAPIError,
+		maxRequestBytes: asyncPayloadLimitBytes,
+	},
+	"sqs": {
+		allowedMethods:  []string{"POST", "PUT"},
+		buildEvent:      buildSQSEvent,
+		writeError:      writeInvokeAPIError,
+		maxRequestBytes: asyncPayloadLimitBytes,
+	},
+	"kinesis": {
+		allowedMethods:   []string{"POST", "PUT"},
+		buildStreamEvent: buildKinesisEvent,
+		writeError:       writeInvokeAPIError,
+		maxRequestBytes:  syncPayloadLimitBytes,
+	},
+	"dynamodb": {
+		allowedMethods:   []string{"POST", "PUT"},
+		buildStreamEvent: buildDynamoDBEvent,
+		writeError:       writeInvokeAPIError,
+		maxRequestBytes:  syncPayloadLimitBytes,
+	},
+}
+
+// selectEventFormat picks the format from the X-Rie-Event-Format header,
+// falling back to AWS_LAMBDA_RIE_EVENT_FORMAT and then to the Function URL format
+func selectEventFormat(r *http.Request) (string, *eventFormat, error) {
+	name := r.Header.Get(eventFormatHeader)
+	if name == "" {
+		name = GetenvWithDefault(eventFormatEnvKey, defaultEventFormat)
+	}
+
+	format, found := eventFormats[name]
+	if !found {
+		return name, nil, fmt.Errorf("unknown event format %q", name)
+	}
+	return name, format, nil
+}
+
+// buildEvents returns the events the request is mapped to, one per invoke
+func (f *eventFormat) buildEvents(r *http.Request, body []byte) ([]interface{}, error) {
+	if f.buildStreamEvent != nil {
+		return streamBatches(r, body, f.buildStreamEvent)
+	}
+
+	event, err := f.buildEvent(r, body)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{event}, nil
+}
+
+// formatEnv reads a setting of the named event format from key_NAME, e.g. AWS_LAMBDA_RIE_ALLOWED_METHODS_SNS or
+// AWS_LAMBDA_RIE_ALLOWED_METHODS_FUNCTION_URL, falling back to key, which applies to all formats. It also returns the
+// name of the variable the setting was read from.
+func formatEnv(key string, formatName string) (string, string) {
+	formatKey := key + "_" + strings.ToUpper(strings.ReplaceAll(formatName, "-", "_"))
+	if configured := GetenvWithDefault(formatKey, ""); configured != "" {
+		return formatKey, configured
+	}
+	return key, GetenvWithDefault(key, "")
+}
+
+func (f *eventFormat) methods(formatName string) []string {
+	if _, configured := formatEnv(allowedMethodsEnvKey, formatName); configured != "" {
+		methods := strings.Split(configured, ",")
+		for i, m := range methods {
+			methods[i] = strings.TrimSpace(m)
+		}
+		return methods
+	}
+	return f.allowedMethods
+}
+
+func (f *eventFormat) allowsMethod(formatName string, method string) bool {
+	for _, m := range f.methods(formatName) {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *eventFormat) requestLimit(formatName string) int64 {
+	key, configured := formatEnv(maxRequestBytesEnvKey, formatName)
+	if configured == "" {
+		return f.maxRequestBytes
+	}
+
+	limit, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || limit <= 0 {
+		log.Warnf("Invalid %s %q, using %d", key, configured, f.maxRequestBytes)
+		return f.maxRequestBytes
+	}
+	return limit
+}
+
+func (f *eventFormat) allowHeader(formatName string) string {
+	return strings.Join(f.methods(formatName), ", ")
+}
+
+type AwsFunctionRequestContext struct {
+	AccountID    string            `json:"accountId"`
+	DomainName   string            `json:"domainName"`
+	DomainPrefix string            `json:"domainPrefix"`
+	Http         map[string]string `json:"http"`
+	// set when an authorizer is simulated, see authorizerContext
+	Authorizer map[string]interface{} `json:"authorizer,omitempty"`
+}
+
+type AwsFunctionRequestPayload struct {
+	Method                string                    `json:"method"`
+	RawPath               string                    `json:"rawPath"`
+	RawQueryString        string                    `json:"rawQueryString"`
+	QueryStringParameters map[string]string         `json:"queryStringParameters,omitempty"`
+	Headers               map[string]string         `json:"headers"`
+	RequestContext        AwsFunctionRequestContext `json:"requestContext"`
+	Body                  string                    `json:"body"`
+	IsBase64Encoded       bool                      `json:"isBase64Encoded"`
+}
+
+// takeQueryBody returns the body given in the ?body= query parameter of a GET request, when
+// AWS_LAMBDA_RIE_ALLOW_QUERY_BODY is true, and removes the parameter from the request
+func takeQueryBody(r *http.Request) []byte {
+	if r.Method != http.MethodGet || GetenvWithDefault(allowQueryBodyEnvKey, "false") != "true" {
+		return nil
+	}
+
+	query := r.URL.Query()
+	if _, found := query[queryBodyParam]; !found {
+		return nil
+	}
+	body := query.Get(queryBodyParam)
+	query.Del(queryBodyParam)
+	r.URL.RawQuery = query.Encode()
+	return []byte(body)
+}
+
+// addDefaultAccept sets the event's Accept header to AWS_LAMBDA_RIE_DEFAULT_ACCEPT when the client sent none
+func addDefaultAccept(r *http.Request, headers map[string]string, headerCase string) {
+	if _, found := r.Header["Accept"]; found {
+		return
+	}
+	if accept := GetenvWithDefault(defaultAcceptEnvKey, ""); accept != "" {
+		headers[eventHeaderName(r, "Accept", headerCase)] = accept
+	}
+}
+
+// wildcardPath returns the path matched by the catch-all route of DirectInvokeHandler, which the events are built for.
+// It fails rather than falling back to / when the route did not capture the request path, e.g. after the route
+// was changed, so that the function is not invoked with the wrong path.
+func wildcardPath(r *http.Request) (string, error) {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil {
+		return "", fmt.Errorf("%w: the request was not routed through the catch-all route", errRouting)
+	}
+
+	// chi routes the escaped path, which the wildcard holds
+	routePath := routeContext.RoutePath
+	if routePath == "" {
+		routePath = r.URL.RawPath
+	}
+	if routePath == "" {
+		routePath = r.URL.Path
+	}
+
+	path := "/" + chi.URLParam(r, "*")
+	if path != routePath {
+		return "", fmt.Errorf("%w: the catch-all route captured %q instead of the request path %q", errRouting, path, routePath)
+	}
+	return path, nil
+}
+
+// buildFunctionURLEvent maps the request to the Function URL (payload format 2.0) event
+// see https://docs.aws.amazon.com/lambda/latest/dg/urls-invocation.html
+func buildFunctionURLEvent(r *http.Request, body []byte) (interface{}, error) {
+	rawPath, err := wildcardPath(r)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := authorizerContext(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := AwsFunctionRequestContext{
+		AccountID:  functionAccountID(),
+		DomainName: r.Host,
+		Http:       map[string]string{},
+		Authorizer: authorizer,
+	}
+	ctx.Http["method"] = r.Method
+	ctx.Http["path"] = rawPath
+	host_split := strings.Split(r.Host, ".")
+	if len(host_split) > 1 {
+		ctx.DomainPrefix = host_split[0]
+	}
+
+	proxy_req := AwsFunctionRequestPayload{
+		Method:         r.Method,
+		RawPath:        rawPath,
+		RawQueryString: r.URL.RawQuery,
+		RequestContext: ctx,
+		Headers:        map[string]string{},
+		Body:           string(body),
+	}
+	if isBinaryBody(r, body) {
+		proxy_req.Body = base64.StdEncoding.EncodeToString(body)
+		proxy_req.IsBase64Encoded = true
+	}
+
+	// like API Gateway v2, repeated parameters are combined with commas in the order they were sent,
+	// and the event has no queryStringParameters without a query
+	if query := r.URL.Query(); len(query) > 0 {
+		proxy_req.QueryStringParameters = map[string]string{}
+		for k, vs := range query {
+			proxy_req.QueryStringParameters[k] = strings.Join(vs, ",")
+		}
+	}
+
+	for k, vs := range r.Header {
+		// like API Gateway v2, Function URLs deliver header names lowercased, and the values
+		// of a repeated header combined into one list as HTTP allows
+		proxy_req.Headers[eventHeaderName(r, k, headerCaseLower)] = strings.Join(vs, ", ")
+	}
+	// net/http moves Host out of r.Header, the frontend delivers it like any other header
+	if r.Host != "" {
+		proxy_req.Headers[eventHeaderName(r, "Host", headerCaseLower)] = r.Host
+	}
+	addDefaultAccept(r, proxy_req.Headers, headerCaseLower)
+
+	return proxy_req, nil
+}
+
+// writeFunctionURLError writes errors the way the Function URL frontend does, e.g. {"Message":"Forbidden"}
+func writeFunctionURLError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"Message": message})
+}
+
+// writeInvokeAPIError writes errors the way the Lambda Invoke API does, for the triggers
+// that are not HTTP endpoints themselves
+func writeInvokeAPIError(w http.ResponseWriter, statusCode int, message string) {
+	errorType := "InvalidRequestContentException"
+	if statusCode == http.StatusRequestEntityTooLarge {
+		errorType = "RequestTooLargeException"
+	}
+	writeJSONError(w, statusCode, errorType, message)
+}
@@ -0,0 +1,157 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	apiGatewayStage        = "test"
+	apiGatewayProxyPath    = "/{proxy+}"
+	apiGatewayRequestTime  = "02/Jan/2006:15:04:05 -0700"
+	defaultAPIGatewayAPIID = "1234567890"
+)
+
+type APIGatewayRequestIdentity struct {
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+type APIGatewayProxyRequestContext struct {
+	AccountID         string                    `json:"accountId"`
+	APIID             string                    `json:"apiId"`
+	DomainName        string                    `json:"domainName"`
+	DomainPrefix      string                    `json:"domainPrefix"`
+	ExtendedRequestID string                    `json:"extendedRequestId"`
+	HTTPMethod        string                    `json:"httpMethod"`
+	Identity          APIGatewayRequestIdentity `json:"identity"`
+	Path              string                    `json:"path"`
+	Protocol          string                    `json:"protocol"`
+	RequestID         string                    `json:"requestId"`
+	RequestTime       string                    `json:"requestTime"`
+	RequestTimeEpoch  int64                     `json:"requestTimeEpoch"`
+	ResourceID        string                    `json:"resourceId"`
+	ResourcePath      string                    `json:"resourcePath"`
+	Stage             string                    `json:"stage"`
+	Authorizer        map[string]interface{}    `json:"authorizer,omitempty"`
+}
+
+type APIGatewayProxyRequest struct {
+	Resource                        string                        `json:"resource"`
+	Path                            string                        `json:"path"`
+	HTTPMethod                      string                        `json:"httpMethod"`
+	Headers                         map[string]string             `json:"headers"`
+	MultiValueHeaders               map[string][]string           `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string             `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string           `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string             `json:"pathParameters"`
+	StageVariables                  map[string]string             `json:"stageVariables"`
+	RequestContext                  APIGatewayProxyRequestContext `json:"requestContext"`
+	Body                            *string                       `json:"body"`
+	IsBase64Encoded                 bool                          `json:"isBase64Encoded"`
+}
+
+// buildAPIGatewayRestEvent maps the request to the event of an API Gateway REST API proxy
+// integration (payload format 1.0) on a greedy {proxy+} resource
+// see https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-lambda-proxy-integrations.html#api-gateway-simple-proxy-for-lambda-input-format
+func buildAPIGatewayRestEvent(r *http.Request, body []byte) (interface{}, error) {
+	path, err := wildcardPath(r)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	requestID := uuid.New().String()
+	authorizer, err := authorizerContext(r)
+	if err != nil {
+		return nil, err
+	}
+
+	event := APIGatewayProxyRequest{
+		Resource:   apiGatewayProxyPath,
+		Path:       path,
+		HTTPMethod: r.Method,
+		RequestContext: APIGatewayProxyRequestContext{
+			AccountID:         functionAccountID(),
+			APIID:             defaultAPIGatewayAPIID,
+			DomainName:        r.Host,
+			ExtendedRequestID: requestID,
+			HTTPMethod:        r.Method,
+			Identity:          APIGatewayRequestIdentity{SourceIP: remoteIP(r), UserAgent: r.UserAgent()},
+			Path:              "/" + apiGatewayStage + path,
+			Protocol:          r.Proto,
+			RequestID:         requestID,
+			RequestTime:       now.Format(apiGatewayRequestTime),
+			RequestTimeEpoch:  now.UnixMilli(),
+			ResourceID:        "proxy",
+			ResourcePath:      apiGatewayProxyPath,
+			Stage:             apiGatewayStage,
+			Authorizer:        authorizer,
+		},
+	}
+	if prefix, _, found := strings.Cut(r.Host, "."); found {
+		event.RequestContext.APIID = prefix
+		event.RequestContext.DomainPrefix = prefix
+	}
+	if path != "/" {
+		event.PathParameters = map[string]string{"proxy": strings.TrimPrefix(path, "/")}
+	}
+
+	// REST APIs deliver headers as sent, headers holds the last value of repeated ones
+	event.Headers = map[string]string{}
+	event.MultiValueHeaders = map[string][]string{}
+	for k, vs := range r.Header {
+		name := eventHeaderName(r, k, headerCasePreserve)
+		event.Headers[name] = vs[len(vs)-1]
+		event.MultiValueHeaders[name] = vs
+	}
+	if r.Host != "" {
+		name := eventHeaderName(r, "Host", headerCasePreserve)
+		event.Headers[name] = r.Host
+		event.MultiValueHeaders[name] = []string{r.Host}
+	}
+	addDefaultAccept(r, event.Headers, headerCasePreserve)
+
+	// without parameters, both query maps are null
+	if query := r.URL.Query(); len(query) > 0 {
+		event.QueryStringParameters = map[string]string{}
+		event.MultiValueQueryStringParameters = map[string][]string{}
+		for k, vs := range query {
+			event.QueryStringParameters[k] = vs[len(vs)-1]
+			event.MultiValueQueryStringParameters[k] = vs
+		}
+	}
+
+	// text bodies, including URL encoded forms, are passed as is, binary ones base64 encoded
+	if len(body) > 0 {
+		text := string(body)
+		if !utf8.Valid(body) {
+			text = base64.StdEncoding.EncodeToString(body)
+			event.IsBase64Encoded = true
+		}
+		event.Body = &text
+	}
+
+	return event, nil
+}
+
+// writeAPIGatewayError writes errors the way API Gateway does, e.g. {"message":"Internal server error"}
+func writeAPIGatewayError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"message": message})
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPIGatewayRestRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(eventFormatHeader, "apigw-rest")
+	return req
+}
+
+func TestDirectInvokeAPIGatewayRestEvent(t *testing.T) {
+	var event APIGatewayProxyRequest
+	req := newAPIGatewayRestRequest("GET", "/orders/42?tag=a&tag=b&page=2", "")
+	req.Host = "abc123.execute-api.us-east-1.amazonaws.com"
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+
+	w := directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, "/{proxy+}", event.Resource)
+	assert.Equal(t, "/orders/42", event.Path)
+	assert.Equal(t, "GET", event.HTTPMethod)
+	assert.Equal(t, map[string]string{"proxy": "orders/42"}, event.PathParameters)
+	assert.Nil(t, event.StageVariables)
+
+	assert.Equal(t, "10.0.0.2", event.Headers["X-Forwarded-For"])
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, event.MultiValueHeaders["X-Forwarded-For"])
+	assert.Equal(t, req.Host, event.Headers["Host"])
+
+	assert.Equal(t, map[string]string{"tag": "b", "page": "2"}, event.QueryStringParameters)
+	assert.Equal(t, map[string][]string{"tag": {"a", "b"}, "page": {"2"}}, event.MultiValueQueryStringParameters)
+
+	ctx := event.RequestContext
+	assert.Equal(t, "abc123", ctx.APIID)
+	assert.Equal(t, req.Host, ctx.DomainName)
+	assert.Equal(t, "/test/orders/42", ctx.Path)
+	assert.Equal(t, "test", ctx.Stage)
+	assert.Equal(t, "/{proxy+}", ctx.ResourcePath)
+	assert.Equal(t, "192.0.2.1", ctx.Identity.SourceIP)
+	assert.NotEmpty(t, ctx.RequestID)
+	assert.NotZero(t, ctx.RequestTimeEpoch)
+
+	assert.Nil(t, event.Body)
+	assert.False(t, event.IsBase64Encoded)
+}
+
+func TestDirectInvokeAPIGatewayRestEventWithoutQuery(t *testing.T) {
+	var event APIGatewayProxyRequest
+
+	directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, newAPIGatewayRestRequest("GET", "/", ""))
+
+	assert.Equal(t, "/", event.Path)
+	assert.Nil(t, event.PathParameters)
+	assert.Nil(t, event.QueryStringParameters)
+	assert.Nil(t, event.MultiValueQueryStringParameters)
+}
+
+func TestDirectInvokeAPIGatewayRestEventBody(t *testing.T) {
+	t.Run("URL encoded form", func(t *testing.T) {
+		var event APIGatewayProxyRequest
+		req := newAPIGatewayRestRequest("POST", "/login", "user=me&next=%2Fhome")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+
+		require.NotNil(t, event.Body)
+		assert.Equal(t, "user=me&next=%2Fhome", *event.Body)
+		assert.False(t, event.IsBase64Encoded)
+		assert.Equal(t, "application/x-www-form-urlencoded", event.Headers["Content-Type"])
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		var event APIGatewayProxyRequest
+		binary := string([]byte{0xff, 0xd8, 0xff, 0xe0})
+
+		directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, newAPIGatewayRestRequest("PUT", "/image", binary))
+
+		require.NotNil(t, event.Body)
+		assert.True(t, event.IsBase64Encoded)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(binary)), *event.Body)
+	})
+}
+
+func TestDirectInvokeAPIGatewayRestError(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := directInvoke(t, sandbox, newAPIGatewayRestRequest("TRACE", "/", ""))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.JSONEq(t, `{"message":"Method Not Allowed"}`, w.Body.String())
+}
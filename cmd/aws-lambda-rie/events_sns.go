@@ -0,0 +1,93 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	snsSubjectParam         = "subject"
+	snsTopicParam           = "topic"
+	snsAttributeParamPrefix = "attribute."
+	defaultSNSTopic         = "test-topic"
+)
+
+type SNSMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+type SNSEntity struct {
+	Type              string                         `json:"Type"`
+	MessageID         string                         `json:"MessageId"`
+	TopicArn          string                         `json:"TopicArn"`
+	Subject           *string                        `json:"Subject"`
+	Message           string                         `json:"Message"`
+	Timestamp         string                         `json:"Timestamp"`
+	SignatureVersion  string                         `json:"SignatureVersion"`
+	Signature         string                         `json:"Signature"`
+	SigningCertURL    string                         `json:"SigningCertUrl"`
+	UnsubscribeURL    string                         `json:"UnsubscribeUrl"`
+	MessageAttributes map[string]SNSMessageAttribute `json:"MessageAttributes"`
+}
+
+type SNSEventRecord struct {
+	EventVersion         string    `json:"EventVersion"`
+	EventSubscriptionArn string    `json:"EventSubscriptionArn"`
+	EventSource          string    `json:"EventSource"`
+	Sns                  SNSEntity `json:"Sns"`
+}
+
+type SNSEvent struct {
+	Records []SNSEventRecord `json:"Records"`
+}
+
+// buildSNSEvent wraps the request body into an SNS notification. The subject, topic name and
+// String message attributes are taken from the query, e.g. ?subject=Hi&attribute.color=blue
+// see https://docs.aws.amazon.com/lambda/latest/dg/with-sns.html
+func buildSNSEvent(r *http.Request, body []byte) (interface{}, error) {
+	query := r.URL.Query()
+	topic := query.Get(snsTopicParam)
+	if topic == "" {
+		topic = defaultSNSTopic
+	}
+	topicArn := fmt.Sprintf("arn:aws:sns:%s:%s:%s", functionRegion(), functionAccountID(), topic)
+	endpoint := fmt.Sprintf("https://sns.%s.amazonaws.com", functionRegion())
+	subscriptionArn := topicArn + ":" + uuid.New().String()
+
+	entity := SNSEntity{
+		Type:              "Notification",
+		MessageID:         uuid.New().String(),
+		TopicArn:          topicArn,
+		Message:           string(body),
+		Timestamp:         time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		SignatureVersion:  "1",
+		Signature:         "EXAMPLE",
+		SigningCertURL:    endpoint + "/SimpleNotificationService-0000000000000000000000.pem",
+		UnsubscribeURL:    endpoint + "/?Action=Unsubscribe&SubscriptionArn=" + subscriptionArn,
+		MessageAttributes: map[string]SNSMessageAttribute{},
+	}
+	if _, found := query[snsSubjectParam]; found {
+		subject := query.Get(snsSubjectParam)
+		entity.Subject = &subject
+	}
+	for k, vs := range query {
+		if name := strings.TrimPrefix(k, snsAttributeParamPrefix); name != k && name != "" {
+			entity.MessageAttributes[name] = SNSMessageAttribute{Type: "String", Value: vs[0]}
+		}
+	}
+
+	return SNSEvent{Records: []SNSEventRecord{{
+		EventVersion:         "1.0",
+		EventSubscriptionArn: subscriptionArn,
+		EventSource:          "aws:sns",
+		Sns:                  entity,
+	}}}, nil
+}
@@ -0,0 +1,58 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectInvokeSNSEvent(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "sns")
+	var event SNSEvent
+	req := httptest.NewRequest("POST", "/?subject=Greeting&attribute.color=blue", strings.NewReader("hello from sns"))
+
+	w := directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Len(t, event.Records, 1)
+	record := event.Records[0]
+	assert.Equal(t, "aws:sns", record.EventSource)
+	assert.Equal(t, "Notification", record.Sns.Type)
+	assert.Equal(t, "hello from sns", record.Sns.Message)
+	assert.Equal(t, "arn:aws:sns:us-east-1:012345678912:test-topic", record.Sns.TopicArn)
+	assert.True(t, strings.HasPrefix(record.EventSubscriptionArn, record.Sns.TopicArn+":"))
+	assert.NotEmpty(t, record.Sns.MessageID)
+	assert.Equal(t, "Greeting", *record.Sns.Subject)
+	assert.Equal(t, map[string]SNSMessageAttribute{"color": {Type: "String", Value: "blue"}}, record.Sns.MessageAttributes)
+}
+
+func TestDirectInvokeSNSEventWithoutSubject(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "sns")
+	var event SNSEvent
+	req := httptest.NewRequest("POST", "/?topic=orders", strings.NewReader("{}"))
+
+	directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+
+	assert.Nil(t, event.Records[0].Sns.Subject)
+	assert.Equal(t, "arn:aws:sns:us-east-1:012345678912:orders", event.Records[0].Sns.TopicArn)
+	assert.Empty(t, event.Records[0].Sns.MessageAttributes)
+}
+
+func TestDirectInvokeSNSRejectsLargeMessage(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "sns")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	tooLarge := strings.Repeat("a", asyncPayloadLimitBytes+1)
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(tooLarge)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "RequestTooLargeException")
+
+	w = directInvoke(t, sandbox, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,87 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sqsQueueParam           = "queue"
+	sqsAttributeParamPrefix = "attribute."
+	defaultSQSQueue         = "test-queue"
+)
+
+type SQSMessageAttribute struct {
+	StringValue      string   `json:"stringValue"`
+	StringListValues []string `json:"stringListValues"`
+	BinaryListValues []string `json:"binaryListValues"`
+	DataType         string   `json:"dataType"`
+}
+
+type SQSMessage struct {
+	MessageID         string                         `json:"messageId"`
+	ReceiptHandle     string                         `json:"receiptHandle"`
+	Body              string                         `json:"body"`
+	Attributes        map[string]string              `json:"attributes"`
+	MessageAttributes map[string]SQSMessageAttribute `json:"messageAttributes"`
+	MD5OfBody         string                         `json:"md5OfBody"`
+	EventSource       string                         `json:"eventSource"`
+	EventSourceARN    string                         `json:"eventSourceARN"`
+	AWSRegion         string                         `json:"awsRegion"`
+}
+
+type SQSEvent struct {
+	Records []SQSMessage `json:"Records"`
+}
+
+// buildSQSEvent wraps the request body into a batch of one SQS message. The queue name and String message
+// attributes are taken from the query, e.g. ?queue=orders&attribute.color=blue
+// see https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html
+func buildSQSEvent(r *http.Request, body []byte) (interface{}, error) {
+	query := r.URL.Query()
+	queue := query.Get(sqsQueueParam)
+	if queue == "" {
+		queue = defaultSQSQueue
+	}
+
+	sent := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	bodyMD5 := md5.Sum(body)
+	message := SQSMessage{
+		MessageID:     uuid.New().String(),
+		ReceiptHandle: uuid.New().String(),
+		Body:          string(body),
+		Attributes: map[string]string{
+			"ApproximateReceiveCount":          "1",
+			"SentTimestamp":                    sent,
+			"SenderId":                         functionAccountID(),
+			"ApproximateFirstReceiveTimestamp": sent,
+		},
+		MessageAttributes: map[string]SQSMessageAttribute{},
+		MD5OfBody:         hex.EncodeToString(bodyMD5[:]),
+		EventSource:       "aws:sqs",
+		EventSourceARN:    fmt.Sprintf("arn:aws:sqs:%s:%s:%s", functionRegion(), functionAccountID(), queue),
+		AWSRegion:         functionRegion(),
+	}
+	for k, vs := range query {
+		if name := strings.TrimPrefix(k, sqsAttributeParamPrefix); name != k && name != "" {
+			message.MessageAttributes[name] = SQSMessageAttribute{
+				StringValue:      vs[0],
+				StringListValues: []string{},
+				BinaryListValues: []string{},
+				DataType:         "String",
+			}
+		}
+	}
+
+	return SQSEvent{Records: []SQSMessage{message}}, nil
+}
@@ -0,0 +1,53 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectInvokeSQSEvent(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "sqs")
+	var event SQSEvent
+	req := httptest.NewRequest("POST", "/?queue=orders&attribute.color=blue", strings.NewReader("Test message."))
+
+	w := directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Len(t, event.Records, 1)
+	message := event.Records[0]
+	assert.Equal(t, "aws:sqs", message.EventSource)
+	assert.Equal(t, "Test message.", message.Body)
+	assert.Equal(t, "e4e68fb7bd0e697a0ae8f1bb342846b3", message.MD5OfBody)
+	assert.Equal(t, "arn:aws:sqs:us-east-1:012345678912:orders", message.EventSourceARN)
+	assert.NotEmpty(t, message.MessageID)
+	assert.Equal(t, "1", message.Attributes["ApproximateReceiveCount"])
+	assert.Equal(t, "String", message.MessageAttributes["color"].DataType)
+	assert.Equal(t, "blue", message.MessageAttributes["color"].StringValue)
+}
+
+func TestDirectInvokeSQSRequestLimit(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "sqs")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	tooLarge := strings.Repeat("a", asyncPayloadLimitBytes+1)
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(tooLarge)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "RequestTooLargeException")
+
+	t.Setenv(maxRequestBytesEnvKey+"_SQS", strconv.Itoa(asyncPayloadLimitBytes+1))
+	t.Setenv(maxRequestBytesEnvKey, "16")
+	w = directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(tooLarge)))
+	assert.Equal(t, http.StatusOK, w.Code, "the format's limit overrides the one of all formats")
+
+	t.Setenv(eventFormatEnvKey, "sns")
+	w = directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("a", 17))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "other formats use the limit of all formats")
+}
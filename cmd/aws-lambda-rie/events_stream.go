@@ -0,0 +1,241 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	batchSizeEnvKey  = "AWS_LAMBDA_RIE_BATCH_SIZE"
+	defaultBatchSize = 100
+	// number of invokes made for a request split in several batches
+	batchCountHeader = "X-Rie-Batch-Count"
+
+	kinesisStreamParam         = "stream"
+	kinesisPartitionKeyParam   = "partitionKey"
+	dynamoDBTableParam         = "table"
+	defaultKinesisStream       = "test-stream"
+	defaultKinesisPartitionKey = "partition-key"
+	defaultDynamoDBTable       = "test-table"
+)
+
+// streamSequenceNumber orders the records of all synthesized stream events
+var streamSequenceNumber atomic.Uint64
+
+func nextSequenceNumber() string {
+	return fmt.Sprintf("%021d", streamSequenceNumber.Add(1))
+}
+
+// streamRecords splits the body into the data of each record: the elements of a JSON array,
+// or else the whole body as a single record
+func streamRecords(body []byte) [][]byte {
+	var elements []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) && json.Unmarshal(body, &elements) == nil {
+		records := make([][]byte, len(elements))
+		for i, element := range elements {
+			records[i] = element
+		}
+		return records
+	}
+	return [][]byte{body}
+}
+
+// batchSize is the largest number of records sent in one invoke, set by AWS_LAMBDA_RIE_BATCH_SIZE
+func batchSize() int {
+	configured := GetenvWithDefault(batchSizeEnvKey, "")
+	if configured == "" {
+		return defaultBatchSize
+	}
+
+	size, err := strconv.Atoi(configured)
+	if err != nil || size <= 0 {
+		log.Warnf("Invalid %s %q, using %d", batchSizeEnvKey, configured, defaultBatchSize)
+		return defaultBatchSize
+	}
+	return size
+}
+
+// streamBatches builds one event per batch of at most batchSize records, like a stream poller does
+func streamBatches(r *http.Request, body []byte, build func(r *http.Request, records [][]byte) (interface{}, error)) ([]interface{}, error) {
+	records := streamRecords(body)
+	if len(records) == 0 {
+		return nil, errors.New("at least one record is required")
+	}
+
+	size := batchSize()
+	events := []interface{}{}
+	for start := 0; start < len(records); start += size {
+		end := start + size
+		if end > len(records) {
+			end = len(records)
+		}
+		event, err := build(r, records[start:end])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+type KinesisRecord struct {
+	KinesisSchemaVersion        string  `json:"kinesisSchemaVersion"`
+	PartitionKey                string  `json:"partitionKey"`
+	SequenceNumber              string  `json:"sequenceNumber"`
+	Data                        string  `json:"data"`
+	ApproximateArrivalTimestamp float64 `json:"approximateArrivalTimestamp"`
+}
+
+type KinesisEventRecord struct {
+	Kinesis           KinesisRecord `json:"kinesis"`
+	EventSource       string        `json:"eventSource"`
+	EventVersion      string        `json:"eventVersion"`
+	EventID           string        `json:"eventID"`
+	EventName         string        `json:"eventName"`
+	InvokeIdentityArn string        `json:"invokeIdentityArn"`
+	AwsRegion         string        `json:"awsRegion"`
+	EventSourceArn    string        `json:"eventSourceARN"`
+}
+
+type KinesisEvent struct {
+	Records []KinesisEventRecord `json:"Records"`
+}
+
+// buildKinesisEvent puts each record, base64 encoded, in a Kinesis record.
+// The stream name and partition key are taken from the query, e.g. ?stream=orders&partitionKey=1
+// see https://docs.aws.amazon.com/lambda/latest/dg/with-kinesis.html
+func buildKinesisEvent(r *http.Request, records [][]byte) (interface{}, error) {
+	query := r.URL.Query()
+	stream := queryWithDefault(query.Get(kinesisStreamParam), defaultKinesisStream)
+	partitionKey := queryWithDefault(query.Get(kinesisPartitionKeyParam), defaultKinesisPartitionKey)
+	streamArn := fmt.Sprintf("arn:aws:kinesis:%s:%s:stream/%s", functionRegion(), functionAccountID(), stream)
+	arrival := float64(time.Now().UnixMilli()) / 1000
+
+	event := KinesisEvent{Records: []KinesisEventRecord{}}
+	for _, data := range records {
+		sequenceNumber := nextSequenceNumber()
+		event.Records = append(event.Records, KinesisEventRecord{
+			Kinesis: KinesisRecord{
+				KinesisSchemaVersion:        "1.0",
+				PartitionKey:                partitionKey,
+				SequenceNumber:              sequenceNumber,
+				Data:                        base64.StdEncoding.EncodeToString(data),
+				ApproximateArrivalTimestamp: arrival,
+			},
+			EventSource:       "aws:kinesis",
+			EventVersion:      "1.0",
+			EventID:           "shardId-000000000000:" + sequenceNumber,
+			EventName:         "aws:kinesis:record",
+			InvokeIdentityArn: fmt.Sprintf("arn:aws:iam::%s:role/lambda-role", functionAccountID()),
+			AwsRegion:         functionRegion(),
+			EventSourceArn:    streamArn,
+		})
+	}
+	return event, nil
+}
+
+// DynamoDBRecordInput is a record as POSTed by the user, the images are in the
+// DynamoDB attribute value format, e.g. {"Id": {"N": "101"}}
+type DynamoDBRecordInput struct {
+	EventName string          `json:"eventName"`
+	Keys      json.RawMessage `json:"Keys"`
+	NewImage  json.RawMessage `json:"NewImage,omitempty"`
+	OldImage  json.RawMessage `json:"OldImage,omitempty"`
+}
+
+type DynamoDBStreamRecord struct {
+	ApproximateCreationDateTime int64           `json:"ApproximateCreationDateTime"`
+	Keys                        json.RawMessage `json:"Keys"`
+	NewImage                    json.RawMessage `json:"NewImage,omitempty"`
+	OldImage                    json.RawMessage `json:"OldImage,omitempty"`
+	SequenceNumber              string          `json:"SequenceNumber"`
+	SizeBytes                   int             `json:"SizeBytes"`
+	StreamViewType              string          `json:"StreamViewType"`
+}
+
+type DynamoDBEventRecord struct {
+	EventID        string               `json:"eventID"`
+	EventName      string               `json:"eventName"`
+	EventVersion   string               `json:"eventVersion"`
+	EventSource    string               `json:"eventSource"`
+	AwsRegion      string               `json:"awsRegion"`
+	DynamoDB       DynamoDBStreamRecord `json:"dynamodb"`
+	EventSourceArn string               `json:"eventSourceARN"`
+}
+
+type DynamoDBEvent struct {
+	Records []DynamoDBEventRecord `json:"Records"`
+}
+
+// buildDynamoDBEvent maps each record to a DynamoDB stream record. The event name
+// defaults to INSERT, MODIFY or REMOVE depending on which images are given. The table name is
+// taken from the query, e.g. ?table=orders
+// see https://docs.aws.amazon.com/lambda/latest/dg/with-ddb.html
+func buildDynamoDBEvent(r *http.Request, records [][]byte) (interface{}, error) {
+	table := queryWithDefault(r.URL.Query().Get(dynamoDBTableParam), defaultDynamoDBTable)
+	streamArn := fmt.Sprintf("arn:aws:dynamodb:%s:%s:table/%s/stream/2015-06-27T00:48:05.899", functionRegion(), functionAccountID(), table)
+
+	event := DynamoDBEvent{Records: []DynamoDBEventRecord{}}
+	for _, data := range records {
+		var input DynamoDBRecordInput
+		if err := json.Unmarshal(data, &input); err != nil {
+			return nil, fmt.Errorf("invalid DynamoDB record: %s", err)
+		}
+		if len(input.Keys) == 0 {
+			return nil, errors.New("invalid DynamoDB record: Keys is required")
+		}
+
+		viewType := "KEYS_ONLY"
+		eventName := "MODIFY"
+		switch {
+		case len(input.NewImage) > 0 && len(input.OldImage) > 0:
+			viewType = "NEW_AND_OLD_IMAGES"
+		case len(input.NewImage) > 0:
+			viewType, eventName = "NEW_IMAGE", "INSERT"
+		case len(input.OldImage) > 0:
+			viewType, eventName = "OLD_IMAGE", "REMOVE"
+		}
+		if input.EventName != "" {
+			eventName = input.EventName
+		}
+
+		event.Records = append(event.Records, DynamoDBEventRecord{
+			EventID:      uuid.New().String(),
+			EventName:    eventName,
+			EventVersion: "1.1",
+			EventSource:  "aws:dynamodb",
+			AwsRegion:    functionRegion(),
+			DynamoDB: DynamoDBStreamRecord{
+				ApproximateCreationDateTime: time.Now().Unix(),
+				Keys:                        input.Keys,
+				NewImage:                    input.NewImage,
+				OldImage:                    input.OldImage,
+				SequenceNumber:              nextSequenceNumber(),
+				SizeBytes:                   len(input.Keys) + len(input.NewImage) + len(input.OldImage),
+				StreamViewType:              viewType,
+			},
+			EventSourceArn: streamArn,
+		})
+	}
+	return event, nil
+}
+
+func queryWithDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
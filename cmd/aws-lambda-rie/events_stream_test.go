@@ -0,0 +1,123 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/interop"
+)
+
+func TestStreamRecords(t *testing.T) {
+	assert.Equal(t, [][]byte{[]byte(`{"a": 1}`)}, streamRecords([]byte(`{"a": 1}`)))
+	assert.Equal(t, [][]byte{[]byte(`{"a":1}`), []byte(`"b"`)}, streamRecords([]byte(`[{"a":1}, "b"]`)))
+	assert.Equal(t, [][]byte{[]byte("[not json")}, streamRecords([]byte("[not json")))
+}
+
+func TestDirectInvokeKinesisEvent(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "kinesis")
+	var event KinesisEvent
+	req := httptest.NewRequest("POST", "/?stream=orders&partitionKey=42", strings.NewReader(`[{"id": 1}, {"id": 2}]`))
+
+	w := directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Len(t, event.Records, 2)
+	for i, data := range []string{`{"id": 1}`, `{"id": 2}`} {
+		record := event.Records[i]
+		assert.Equal(t, "aws:kinesis", record.EventSource)
+		assert.Equal(t, "42", record.Kinesis.PartitionKey)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(data)), record.Kinesis.Data)
+		assert.Equal(t, "arn:aws:kinesis:us-east-1:012345678912:stream/orders", record.EventSourceArn)
+	}
+	assert.Less(t, event.Records[0].Kinesis.SequenceNumber, event.Records[1].Kinesis.SequenceNumber)
+}
+
+func TestDirectInvokeDynamoDBEvent(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "dynamodb")
+	var event DynamoDBEvent
+	body := `[
+		{"Keys": {"Id": {"N": "1"}}, "NewImage": {"Id": {"N": "1"}, "Name": {"S": "new"}}},
+		{"Keys": {"Id": {"N": "1"}}, "NewImage": {"Name": {"S": "new"}}, "OldImage": {"Name": {"S": "old"}}},
+		{"Keys": {"Id": {"N": "1"}}, "OldImage": {"Name": {"S": "old"}}}
+	]`
+
+	w := directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, httptest.NewRequest("POST", "/?table=users", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Len(t, event.Records, 3)
+	assert.Equal(t, "INSERT", event.Records[0].EventName)
+	assert.Equal(t, "NEW_IMAGE", event.Records[0].DynamoDB.StreamViewType)
+	assert.JSONEq(t, `{"Id": {"N": "1"}}`, string(event.Records[0].DynamoDB.Keys))
+	assert.Equal(t, "MODIFY", event.Records[1].EventName)
+	assert.Equal(t, "NEW_AND_OLD_IMAGES", event.Records[1].DynamoDB.StreamViewType)
+	assert.Equal(t, "REMOVE", event.Records[2].EventName)
+	assert.JSONEq(t, `{"Name": {"S": "old"}}`, string(event.Records[2].DynamoDB.OldImage))
+	assert.True(t, strings.HasPrefix(event.Records[0].EventSourceArn, "arn:aws:dynamodb:us-east-1:012345678912:table/users/stream/"))
+}
+
+func TestDirectInvokeDynamoDBRejectsInvalidRecord(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "dynamodb")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(`{"NewImage": {}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Keys is required")
+	assert.Equal(t, 0, sandbox.initCalls)
+}
+
+func TestDirectInvokeSplitsStreamRecordsInBatches(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "kinesis")
+	t.Setenv(batchSizeEnvKey, "2")
+	var batchSizes []int
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		var event KinesisEvent
+		if err := json.NewDecoder(i.Payload).Decode(&event); err != nil {
+			return err
+		}
+		batchSizes = append(batchSizes, len(event.Records))
+		w.Write([]byte(`"ok"`))
+		return nil
+	}}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(`[1, 2, 3, 4, 5]`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{2, 2, 1}, batchSizes)
+	assert.Equal(t, "3", w.Header().Get(batchCountHeader))
+
+	batchSizes = nil
+	w = directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(`[1, 2]`)))
+	assert.Equal(t, []int{2}, batchSizes, "a batch at the boundary is sent in one invoke")
+	assert.Empty(t, w.Header().Get(batchCountHeader))
+}
+
+func TestDirectInvokeStopsBatchesAtFunctionError(t *testing.T) {
+	t.Setenv(eventFormatEnvKey, "dynamodb")
+	t.Setenv(batchSizeEnvKey, "1")
+	invokes := 0
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		invokes++
+		return respondWithFunctionError(`{"errorMessage": "boom"}`)(w, i)
+	}}
+
+	records := `[{"Keys": {"Id": {"N": "1"}}}, {"Keys": {"Id": {"N": "2"}}}]`
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/", strings.NewReader(records)))
+	assert.Equal(t, 1, invokes)
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+	assert.Equal(t, "1", w.Header().Get(batchCountHeader))
+}
+
+func TestBatchSize(t *testing.T) {
+	assert.Equal(t, defaultBatchSize, batchSize())
+	t.Setenv(batchSizeEnvKey, "10")
+	assert.Equal(t, 10, batchSize())
+	t.Setenv(batchSizeEnvKey, "0")
+	assert.Equal(t, defaultBatchSize, batchSize())
+}
@@ -0,0 +1,242 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	functionsEnvKey      = "AWS_LAMBDA_RIE_FUNCTIONS"
+	functionInvokePath   = "/2015-03-31/functions/{name}/invocations"
+	defaultFunctionName  = "test_function"
+	functionNotFoundType = "ResourceNotFoundException"
+
+	// how long a function's emulator is given to listen before startup fails
+	functionStartTimeout = 10 * time.Second
+)
+
+// functionNamePattern is the format Lambda accepts for function names
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// functionSpec is a function hosted next to the default one, configured as name=handler in AWS_LAMBDA_RIE_FUNCTIONS
+type functionSpec struct {
+	name    string
+	handler string
+}
+
+// parseFunctions reads the comma separated name=handler list of AWS_LAMBDA_RIE_FUNCTIONS
+func parseFunctions(value string) ([]functionSpec, error) {
+	var specs []functionSpec
+	seen := map[string]bool{"function": true, functionName(): true}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, handler, _ := strings.Cut(entry, "=")
+		name, handler = strings.TrimSpace(name), strings.TrimSpace(handler)
+		if !functionNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid function name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("function %q is already defined", name)
+		}
+		seen[name] = true
+		specs = append(specs, functionSpec{name: name, handler: handler})
+	}
+	return specs, nil
+}
+
+// functionRouter hosts the functions of AWS_LAMBDA_RIE_FUNCTIONS. Each one runs in an emulator process
+// of its own, started with the command line of this one, so that it gets its own runtime, extensions
+// and environment like a Lambda function does, and its invokes are proxied to it.
+type functionRouter struct {
+	proxies  map[string]http.Handler
+	children []*functionProcess
+	stopping atomic.Bool
+}
+
+type functionProcess struct {
+	name   string
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// startFunctions starts an emulator for each function, args are the positional arguments of this one.
+// With secure, the emulators serve HTTPS like this one, from the same command line.
+func startFunctions(specs []functionSpec, args []string, secure bool) (*functionRouter, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	router := &functionRouter{proxies: map[string]http.Handler{}}
+	for _, spec := range specs {
+		address, err := freeLocalAddress()
+		if err != nil {
+			return nil, err
+		}
+		runtimeAPIAddress, err := freeLocalAddress()
+		if err != nil {
+			return nil, err
+		}
+
+		cmd := exec.Command(os.Args[0], functionArgs(os.Args[1:], args, spec.handler, address, runtimeAPIAddress)...)
+		cmd.Env = functionEnv(os.Environ(), spec)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			router.stop()
+			return nil, fmt.Errorf("failed to start function %s: %s", spec.name, err)
+		}
+		child := &functionProcess{name: spec.name, cmd: cmd, exited: make(chan struct{})}
+		router.children = append(router.children, child)
+		go func() {
+			err := child.cmd.Wait()
+			close(child.exited)
+			if router.stopping.Load() {
+				log.Infof("The emulator of function %s exited: %v", child.name, err)
+				return
+			}
+			log.Errorf("The emulator of function %s exited unexpectedly, its invokes fail until restarting: %v", child.name, err)
+		}()
+
+		if err := awaitListening(address, child.exited); err != nil {
+			router.stop()
+			return nil, fmt.Errorf("function %s did not start: %s", spec.name, err)
+		}
+		log.Infof("Function %s (handler %q) is invoked through %s", spec.name, spec.handler, strings.Replace(functionInvokePath, "{name}", spec.name, 1))
+		router.proxies[spec.name] = newFunctionProxy(spec.name, functionURL(address, secure))
+	}
+	return router, nil
+}
+
+// functionArgs replaces the positional handler and the addresses of this emulator's command line. The runtime
+// interface clients read the handler from their arguments, so the one of the function takes its place; without a
+// positional handler, the function's is only passed in its environment.
+func functionArgs(commandLine []string, positional []string, handler string, address string, runtimeAPIAddress string) []string {
+	functionCommandLine := append([]string{}, commandLine...)
+	if len(positional) > 2 {
+		positionalHandler := positional[len(positional)-1]
+		for i := len(functionCommandLine) - 1; i >= 0; i-- {
+			if functionCommandLine[i] == positionalHandler {
+				functionCommandLine[i] = handler
+				break
+			}
+		}
+	}
+	return append(functionCommandLine, "--listen", address, "--runtime-api-address", runtimeAPIAddress)
+}
+
+// functionEnv is the environment of this emulator with the name and handler of the function
+func functionEnv(environ []string, spec functionSpec) []string {
+	var env []string
+	for _, variable := range environ {
+		switch key, _, _ := strings.Cut(variable, "="); key {
+		case functionsEnvKey, startupDelayEnvKey, "AWS_LAMBDA_FUNCTION_NAME", functionHandlerEnvKey:
+		default:
+			env = append(env, variable)
+		}
+	}
+	return append(env, "AWS_LAMBDA_FUNCTION_NAME="+spec.name, functionHandlerEnvKey+"="+spec.handler)
+}
+
+func freeLocalAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
+
+// awaitListening waits until a function's emulator accepts connections, or exited
+func awaitListening(address string, exited <-chan struct{}) error {
+	deadline := time.Now().Add(functionStartTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-exited:
+			return fmt.Errorf("its emulator exited")
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", address, 100*time.Millisecond); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("its emulator is not listening on %s after %s", address, functionStartTimeout)
+}
+
+func functionURL(address string, secure bool) *url.URL {
+	if secure {
+		return &url.URL{Scheme: "https", Host: address}
+	}
+	return &url.URL{Scheme: "http", Host: address}
+}
+
+// newFunctionProxy forwards invokes to the invoke endpoint of a function's emulator
+func newFunctionProxy(name string, target *url.URL) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	direct := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		direct(r)
+		r.URL.Path, r.URL.RawPath = invokePath, ""
+	}
+	if target.Scheme == "https" {
+		// a function's emulator uses the certificate of this one, or a self-signed one of its own, on a local port
+		proxy.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.WithError(err).Errorf("Failed to invoke function %s", name)
+		writeJSONError(w, http.StatusBadGateway, serviceErrorType, fmt.Sprintf("Function %s is not available: %s", name, err))
+	}
+	return proxy
+}
+
+// handler routes the invokes of /2015-03-31/functions/{name}/invocations, defaultInvoke handles the
+// function of this emulator, known by its name as well as by "function"
+func (f *functionRouter) handler(defaultInvoke http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if proxy, found := f.proxies[name]; found {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		if name == functionName() {
+			defaultInvoke.ServeHTTP(w, r)
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, functionNotFoundType, "Function not found: "+functionArnOf(name))
+	}
+}
+
+// stop is registered with SandboxBuilder.AddDrainFunc, the emulators of the functions drain their own invokes
+func (f *functionRouter) stop() {
+	if f == nil {
+		return
+	}
+	f.stopping.Store(true)
+	for _, child := range f.children {
+		child.cmd.Process.Signal(syscall.SIGTERM)
+	}
+	for _, child := range f.children {
+		<-child.exited
+	}
+}
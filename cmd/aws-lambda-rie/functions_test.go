@@ -0,0 +1,88 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFunctions(t *testing.T) {
+	specs, err := parseFunctions(" orders=app.orders, users=app.users,,")
+	require.NoError(t, err)
+	assert.Equal(t, []functionSpec{{name: "orders", handler: "app.orders"}, {name: "users", handler: "app.users"}}, specs)
+
+	specs, err = parseFunctions("")
+	require.NoError(t, err)
+	assert.Empty(t, specs)
+
+	for _, value := range []string{"orders=a,orders=b", "function=app.handler", "test_function=app.handler", "my function=app.handler", "=app.handler"} {
+		_, err := parseFunctions(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFunctionArgs(t *testing.T) {
+	args := functionArgs([]string{"--log-level", "debug", "/var/runtime/bootstrap", "app.handler"}, []string{"rie", "/var/runtime/bootstrap", "app.handler"}, "app.orders", "127.0.0.1:1000", "127.0.0.1:2000")
+	assert.Equal(t, []string{"--log-level", "debug", "/var/runtime/bootstrap", "app.orders", "--listen", "127.0.0.1:1000", "--runtime-api-address", "127.0.0.1:2000"}, args)
+
+	// the runtime interface client gets the handler of the function as its argument
+	args = functionArgs([]string{"/usr/local/bin/python", "-m", "awslambdaric", "app.handler"}, []string{"rie", "/usr/local/bin/python", "-m", "awslambdaric", "app.handler"}, "app.orders", "127.0.0.1:1000", "127.0.0.1:2000")
+	assert.Equal(t, []string{"/usr/local/bin/python", "-m", "awslambdaric", "app.orders", "--listen", "127.0.0.1:1000", "--runtime-api-address", "127.0.0.1:2000"}, args)
+
+	// without a positional handler the bootstrap is kept as is
+	args = functionArgs([]string{"/var/runtime/bootstrap"}, []string{"rie", "/var/runtime/bootstrap"}, "app.orders", "127.0.0.1:1000", "127.0.0.1:2000")
+	assert.Equal(t, []string{"/var/runtime/bootstrap", "--listen", "127.0.0.1:1000", "--runtime-api-address", "127.0.0.1:2000"}, args)
+}
+
+func TestFunctionEnv(t *testing.T) {
+	env := functionEnv([]string{"AWS_REGION=eu-west-1", functionsEnvKey + "=orders=app.orders", "AWS_LAMBDA_FUNCTION_NAME=main", functionHandlerEnvKey + "=app.main"}, functionSpec{name: "orders", handler: "app.orders"})
+	assert.Equal(t, []string{"AWS_REGION=eu-west-1", "AWS_LAMBDA_FUNCTION_NAME=orders", functionHandlerEnvKey + "=app.orders"}, env)
+}
+
+func TestFunctionRouter(t *testing.T) {
+	orders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.URL.Path + " " + string(body)))
+	}))
+	defer orders.Close()
+	target, _ := url.Parse(orders.URL)
+	gone := httptest.NewServer(http.NotFoundHandler())
+	goneTarget, _ := url.Parse(gone.URL)
+	gone.Close()
+
+	functions := &functionRouter{proxies: map[string]http.Handler{
+		"orders": newFunctionProxy("orders", target),
+		"gone":   newFunctionProxy("gone", goneTarget),
+	}}
+	r := chi.NewRouter()
+	r.Post(functionInvokePath, functions.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("default")) })))
+	post := func(name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/2015-03-31/functions/"+name+"/invocations", strings.NewReader(`{"id": 1}`)))
+		return w
+	}
+
+	w := post("orders")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, invokePath+` {"id": 1}`, w.Body.String())
+
+	assert.Equal(t, "default", post("test_function").Body.String())
+
+	w = post("unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"errorType": "ResourceNotFoundException", "errorMessage": "Function not found: arn:aws:lambda:us-east-1:012345678912:function:unknown"}`, w.Body.String())
+
+	w = post("gone")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "ServiceException")
+}
@@ -0,0 +1,54 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+)
+
+const (
+	functionHandlerEnvKey = "AWS_LAMBDA_FUNCTION_HANDLER"
+	handlerEnvKey         = "_HANDLER"
+	requireHandlerEnvKey  = "AWS_LAMBDA_RIE_REQUIRE_HANDLER"
+
+	handlerSourcePositional = "positional argument"
+	handlerNotFoundType     = "Runtime.HandlerNotFound"
+
+	noHandlerHelp = "No handler is configured: pass it after the bootstrap command (e.g. aws-lambda-rie /var/runtime/bootstrap app.handler) " +
+		"or set " + functionHandlerEnvKey + " or " + handlerEnvKey + ". Custom runtimes that do not read a handler can ignore this."
+)
+
+var errNoHandler = errors.New("no handler configured")
+
+// positionalHandler is the handler given as the last command line argument, set once at startup
+var positionalHandler string
+
+// resolveHandler returns the function handler and where it was configured. Like the official
+// emulator, a positional handler wins over AWS_LAMBDA_FUNCTION_HANDLER, which wins over _HANDLER.
+func resolveHandler(positional string) (string, string, error) {
+	if positional != "" {
+		return positional, handlerSourcePositional, nil
+	}
+	for _, key := range []string{functionHandlerEnvKey, handlerEnvKey} {
+		if handler := os.Getenv(key); handler != "" {
+			return handler, key, nil
+		}
+	}
+	return "", "", errNoHandler
+}
+
+// rejectWithoutHandler answers the invoke with a 500 when no handler is configured and AWS_LAMBDA_RIE_REQUIRE_HANDLER
+// is true, rather than letting the runtime fail on an empty handler with an error that is hard to trace back
+func rejectWithoutHandler(w http.ResponseWriter) bool {
+	if GetenvWithDefault(requireHandlerEnvKey, "false") != "true" {
+		return false
+	}
+	if _, _, err := resolveHandler(positionalHandler); err == nil {
+		return false
+	}
+	writeJSONError(w, http.StatusInternalServerError, handlerNotFoundType, noHandlerHelp)
+	return true
+}
@@ -0,0 +1,96 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unsetHandlerEnv(t *testing.T) {
+	for _, key := range []string{functionHandlerEnvKey, handlerEnvKey} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestResolveHandlerFromEnvOnly(t *testing.T) {
+	unsetHandlerEnv(t)
+	t.Setenv(functionHandlerEnvKey, "app.env_handler")
+
+	handler, source, err := resolveHandler("")
+	assert.NoError(t, err)
+	assert.Equal(t, "app.env_handler", handler)
+	assert.Equal(t, functionHandlerEnvKey, source)
+}
+
+func TestResolveHandlerFromLegacyEnvOnly(t *testing.T) {
+	unsetHandlerEnv(t)
+	t.Setenv(handlerEnvKey, "app.legacy_handler")
+
+	handler, source, err := resolveHandler("")
+	assert.NoError(t, err)
+	assert.Equal(t, "app.legacy_handler", handler)
+	assert.Equal(t, handlerEnvKey, source)
+}
+
+func TestResolveHandlerFromPositionalOnly(t *testing.T) {
+	unsetHandlerEnv(t)
+
+	handler, source, err := resolveHandler("app.cli_handler")
+	assert.NoError(t, err)
+	assert.Equal(t, "app.cli_handler", handler)
+	assert.Equal(t, handlerSourcePositional, source)
+}
+
+func TestResolveHandlerPositionalWinsOverEnv(t *testing.T) {
+	unsetHandlerEnv(t)
+	t.Setenv(functionHandlerEnvKey, "app.env_handler")
+	t.Setenv(handlerEnvKey, "app.legacy_handler")
+
+	handler, source, err := resolveHandler("app.cli_handler")
+	assert.NoError(t, err)
+	assert.Equal(t, "app.cli_handler", handler)
+	assert.Equal(t, handlerSourcePositional, source)
+}
+
+func TestResolveHandlerWithoutHandler(t *testing.T) {
+	unsetHandlerEnv(t)
+
+	_, _, err := resolveHandler("")
+	assert.ErrorIs(t, err, errNoHandler)
+}
+
+func TestInitHandlerUsesPositionalHandler(t *testing.T) {
+	unsetHandlerEnv(t)
+	t.Setenv(functionHandlerEnvKey, "app.env_handler")
+	positionalHandler = "app.cli_handler"
+	t.Cleanup(func() { positionalHandler = "" })
+
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, "app.cli_handler", sandbox.lastInit.Handler)
+}
+
+func TestInvokeWithoutHandler(t *testing.T) {
+	unsetHandlerEnv(t)
+
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	assert.Equal(t, `"ok"`, invoke(t, sandbox, newInvokeRequest("{}")).Body.String(), "custom runtimes may not need a handler")
+
+	t.Setenv(requireHandlerEnvKey, "true")
+	sandbox = &mockSandbox{invoke: respondWith(`"ok"`)}
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), handlerNotFoundType)
+	assert.Contains(t, w.Body.String(), functionHandlerEnvKey)
+	assert.Zero(t, sandbox.initCalls, "the runtime is not started without a handler")
+
+	t.Setenv(handlerEnvKey, "app.handler")
+	assert.Equal(t, `"ok"`, invoke(t, sandbox, newInvokeRequest("{}")).Body.String())
+}
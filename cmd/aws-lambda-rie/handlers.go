@@ -5,23 +5,27 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go.amzn.com/lambda/core/directinvoke"
 	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/fatalerror"
 	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/metering"
 	"go.amzn.com/lambda/rapidcore"
 	"go.amzn.com/lambda/rapidcore/env"
 
-	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 
 	"io"
@@ -49,6 +53,49 @@ type InteropServer interface {
 
 var initDone bool
 
+// initFailed is set after an init that did not complete. rapid initializes the function again within the next
+// invoke, so Init is not repeated and the flag only makes the function not ready until an invoke initializes it.
+var initFailed bool
+
+// initMutex guards initDone and initFailed, so that concurrent first invokes wait for a single Init
+var initMutex sync.Mutex
+
+func setInitFailed(failed bool) {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	initFailed = failed
+}
+
+const (
+	functionErrorHeader        = "X-Amz-Function-Error"
+	functionErrorUnhandled     = "Unhandled"
+	functionErrorStatusEnvKey  = "AWS_LAMBDA_RIE_FUNCTION_ERROR_STATUS"
+	defaultFunctionErrorStatus = http.StatusOK
+	functionTimeoutErrorType   = "Sandbox.Timedout"
+	// errors of the emulator rather than of the function, like Lambda's service errors
+	serviceErrorType = "ServiceException"
+
+	accountIDEnvKey  = "AWS_LAMBDA_RIE_ACCOUNT_ID"
+	defaultAccountID = "012345678912"
+	defaultRegion    = "us-east-1"
+
+	initWarnMsEnvKey   = "AWS_LAMBDA_RIE_INIT_WARN_MS"
+	slowInvokeMsEnvKey = "AWS_LAMBDA_RIE_SLOW_INVOKE_MS"
+
+	runtimeEnvKey  = "AWS_LAMBDA_RIE_RUNTIME"
+	defaultRuntime = "provided"
+
+	timezoneEnvKey  = "AWS_LAMBDA_RIE_TZ"
+	defaultTimezone = ":UTC"
+
+	executedRuntimeHeader = "X-Amz-Executed-Runtime"
+	runtimeAPIEnvKey      = "AWS_LAMBDA_RUNTIME_API"
+	strictReservedEnvKey  = "AWS_LAMBDA_RIE_STRICT_RESERVED_ENV"
+
+	billedDurationHeaderEnvKey = "AWS_LAMBDA_RIE_BILLED_DURATION_HEADER"
+	billedDurationHeader       = "X-Amz-Rie-Billed-Duration-Ms"
+)
+
 func GetenvWithDefault(key string, defaultValue string) string {
 	envValue := os.Getenv(key)
 
@@ -59,39 +106,76 @@ func GetenvWithDefault(key string, defaultValue string) string {
 	return envValue
 }
 
-func printEndReports(invokeId string, initDuration string, memorySize string, invokeStart time.Time, timeoutDuration time.Duration) {
-	// Calcuation invoke duration
-	invokeDuration := math.Min(float64(time.Now().Sub(invokeStart).Nanoseconds()),
-		float64(timeoutDuration.Nanoseconds())) / float64(time.Millisecond)
+// printEndReports prints the END and REPORT lines of an invoke and returns its billed duration in milliseconds.
+// status is that of Lambda's JSON REPORT record: success, error or timeout.
+func printEndReports(invokeId string, initDurationMs float64, memorySize string, invokeStart time.Time, timeoutDuration time.Duration, status string, tags string) int64 {
+	invokeDuration := invokeDurationMs(invokeStart, timeoutDuration)
+
+	// timeouts are logged on their own
+	if invokeDuration < float64(timeoutDuration.Milliseconds()) {
+		warnSlowInvoke(invokeId, invokeDuration)
+	}
 
-	fmt.Println("END RequestId: " + invokeId)
-	// We set the Max Memory Used and Memory Size to be the same (whatever it is set to) since there is
-	// not a clean way to get this information from rapidcore
-	fmt.Printf(
+	// the duration includes the time the extensions took after the runtime, which the overhead breaks out
+	spans := platformEvents.takeReportSpans(invokeId)
+	if platformLogFormat == logFormatJSON {
+		printReportEvent(invokeId, initDurationMs, invokeDuration, memorySize, status, spans)
+		return int64(math.Ceil(invokeDuration))
+	}
+
+	extensionOverhead := ""
+	if overheadMs, found := extensionOverheadMs(spans); found {
+		extensionOverhead = fmt.Sprintf("Extension Overhead: %.2f ms\t", overheadMs)
+	}
+	fmt.Fprintln(platformLog, "END RequestId: "+invokeId)
+	fmt.Fprintf(platformLog,
 		"REPORT RequestId: %s\t"+
-			initDuration+
+			"Init Duration: %.2f ms\t"+
 			"Duration: %.2f ms\t"+
 			"Billed Duration: %.f ms\t"+
 			"Memory Size: %s MB\t"+
-			"Max Memory Used: %s MB\t\n",
-		invokeId, invokeDuration, math.Ceil(invokeDuration), memorySize, memorySize)
+			"Max Memory Used: %s MB\t"+
+			"%s%s\n",
+		invokeId, initDurationMs, invokeDuration, math.Ceil(invokeDuration), memorySize, maxMemoryUsed(memorySize), extensionOverhead, tags)
+	return int64(math.Ceil(invokeDuration))
 }
 
-type AwsFunctionRequestContext struct {
-	DomainName   string            `json:"domainName"`
-	DomainPrefix string            `json:"domainPrefix"`
-	Http         map[string]string `json:"http"`
+// invokeDurationMs is the duration of an invoke so far, at most its timeout
+func invokeDurationMs(invokeStart time.Time, timeoutDuration time.Duration) float64 {
+	return math.Min(float64(time.Now().Sub(invokeStart).Nanoseconds()),
+		float64(timeoutDuration.Nanoseconds())) / float64(time.Millisecond)
 }
 
-type AwsFunctionRequestPayload struct {
-	Method                string                    `json:"method"`
-	RawPath               string                    `json:"rawPath"`
-	RawQueryString        string                    `json:"rawQueryString"`
-	QueryStringParameters map[string]string         `json:"queryStringParameters"`
-	Headers               map[string]string         `json:"headers"`
-	RequestContext        AwsFunctionRequestContext `json:"requestContext"`
-	Body                  string                    `json:"body"`
-	IsBase64Encoded       bool                      `json:"isBase64Encoded"`
+// setBilledDurationHeader adds the billed duration to the invoke response when AWS_LAMBDA_RIE_BILLED_DURATION_HEADER
+// is true, so that clients can read it without parsing the REPORT line
+func setBilledDurationHeader(w http.ResponseWriter, billedMs int64) {
+	if GetenvWithDefault(billedDurationHeaderEnvKey, "false") == "true" {
+		w.Header().Set(billedDurationHeader, strconv.FormatInt(billedMs, 10))
+	}
+}
+
+// functionAccountID and functionRegion are the single source for the account and region
+// used in the function ARN and in the synthesized events, so that both always agree
+func functionAccountID() string {
+	return GetenvWithDefault(accountIDEnvKey, defaultAccountID)
+}
+
+func functionRegion() string {
+	return GetenvWithDefault("AWS_REGION", defaultRegion)
+}
+
+// functionName is the name of the function, AWS_LAMBDA_FUNCTION_NAME, which the emulator sets for each function
+// of AWS_LAMBDA_RIE_FUNCTIONS
+func functionName() string {
+	return GetenvWithDefault("AWS_LAMBDA_FUNCTION_NAME", defaultFunctionName)
+}
+
+func functionArn() string {
+	return functionArnOf(functionName())
+}
+
+func functionArnOf(name string) string {
+	return fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", functionRegion(), functionAccountID(), name)
 }
 
 // invoke lambda function in function-url style
@@ -101,74 +185,150 @@ func DirectInvokeHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox
 	// the `DirectInvokeHandler` simply maps request to event object and pass it to `InvokeHandler`
 
 	log.Debugf("invoke: -> %s %s %v", r.Method, r.URL, r.Header)
-	bodyBytes, err := ioutil.ReadAll(r.Body)
+	formatName, format, err := selectEventFormat(r)
 	if err != nil {
-		log.Errorf("Failed to read invoke body: %s", err)
-		w.WriteHeader(500)
+		log.Errorf("Failed to select event format: %s", err)
+		writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), err.Error())
 		return
 	}
 
-	rawPath := "/" + chi.URLParam(r, "*")
+	if !format.allowsMethod(formatName, r.Method) {
+		log.Warnf("Rejected %s request, method not allowed for event format %s", r.Method, formatName)
+		w.Header().Set("Allow", format.allowHeader(formatName))
+		format.writeError(w, http.StatusMethodNotAllowed, methodNotAllowedError)
+		return
+	}
 
-	ctx := AwsFunctionRequestContext{
-		DomainName: r.Host,
-		Http:       map[string]string{},
+	bodyBytes, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, format.requestLimit(formatName)))
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		log.Warnf("Rejected %s request, body exceeds the %d bytes limit of event format %s", r.Method, maxBytesErr.Limit, formatName)
+		format.writeError(w, http.StatusRequestEntityTooLarge, requestTooLargeError)
+		return
 	}
-	ctx.Http["method"] = r.Method
-	ctx.Http["path"] = rawPath
-	host_split := strings.Split(r.Host, ".")
-	if len(host_split) > 1 {
-		ctx.DomainPrefix = host_split[0]
+	if err != nil {
+		log.Errorf("Failed to read invoke body: %s", err)
+		writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
+		return
 	}
 
-	proxy_req := AwsFunctionRequestPayload{
-		Method:                r.Method,
-		RawPath:               rawPath,
-		RawQueryString:        r.URL.RawQuery,
-		QueryStringParameters: map[string]string{},
-		RequestContext:        ctx,
-		Headers:               map[string]string{},
-		Body:                  base64.StdEncoding.EncodeToString(bodyBytes),
-		IsBase64Encoded:       true,
+	if len(bodyBytes) == 0 {
+		bodyBytes = takeQueryBody(r)
 	}
 
-	for k, vs := range r.URL.Query() {
-		proxy_req.QueryStringParameters[k] = strings.Join(vs, ",")
+	events, err := format.buildEvents(r, bodyBytes)
+	if errors.Is(err, errRouting) {
+		// a bug of the emulator rather than of the request
+		log.Errorf("Failed to build %s event, the request path is unknown: %s", formatName, err)
+		writeJSONError(w, http.StatusInternalServerError, routingErrorType, err.Error())
+		return
 	}
-
-	for k, vs := range r.Header {
-		proxy_req.Headers[k] = strings.Join(vs, ",")
+	if err != nil {
+		log.Errorf("Failed to build %s event: %s", formatName, err)
+		format.writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	bodyBytes, err = json.Marshal(proxy_req)
-	if err != nil {
-		log.Errorf("Failed json.Marshal proxy_req: %s", err)
-		w.WriteHeader(500)
+	if format.responseEnvelope && invokeMode() == invokeModeResponseStream {
+		bodyBytes, err = json.Marshal(events[0])
+		if err != nil {
+			log.Errorf("Failed json.Marshal proxy_req: %s", err)
+			writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
+			return
+		}
+		if eventTooLarge(w, format, formatName, bodyBytes) {
+			return
+		}
+		replaceBody(r, bodyBytes)
+
+		stream := newStreamingResponse(w)
+		InvokeHandler(stream, r, sandbox, bs)
+		stream.finish()
 		return
 	}
 
-	var buf bytes.Buffer
-	buf.Write(bodyBytes)
-	r.Body = io.NopCloser(io.Reader(&buf))
-	r.Header.Set("Content-Length", fmt.Sprint(len(bodyBytes)))
+	// batches are invoked in order and, like a stream poller, the first failing one stops the
+	// others; the client gets the response of the last invoke
+	var resp *bufferedResponse
+	for i, event := range events {
+		bodyBytes, err = json.Marshal(event)
+		if err != nil {
+			log.Errorf("Failed json.Marshal proxy_req: %s", err)
+			writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
+			return
+		}
+		if eventTooLarge(w, format, formatName, bodyBytes) {
+			return
+		}
+
+		replaceBody(r, bodyBytes)
+
+		resp = newBufferedResponse()
+		InvokeHandler(resp, r, sandbox, bs)
+		if len(events) > 1 {
+			resp.header.Set(batchCountHeader, strconv.Itoa(i+1))
+		}
+		if resp.statusCode != http.StatusOK || resp.header.Get(functionErrorHeader) != "" {
+			break
+		}
+	}
+	writeDirectResponse(w, resp, format)
+}
+
+// eventTooLarge rejects events larger than maxPayloadBytes, which a body below the limit of the trigger still
+// makes once it is base64 encoded or escaped in the event
+func eventTooLarge(w http.ResponseWriter, format *eventFormat, formatName string, event []byte) bool {
+	if int64(len(event)) <= maxPayloadBytes {
+		return false
+	}
+	log.Warnf("Rejected %s event of %d bytes, it exceeds the %d bytes payload limit", formatName, len(event), maxPayloadBytes)
+	format.writeError(w, http.StatusRequestEntityTooLarge, requestTooLargeError)
+	return true
+}
 
-	InvokeHandler(w, r, sandbox, bs)
+// replaceBody swaps the request body for the synthesized event. The framing of the
+// original request (a chunked Transfer-Encoding or its Content-Length) no longer applies.
+func replaceBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.TransferEncoding = nil
+	r.Header.Del("Transfer-Encoding")
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
 }
 
 func InvokeHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs interop.Bootstrap) {
 	log.Debugf("invoke: -> %s %s %v", r.Method, r.URL, r.Header)
-	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if rejectWithoutHandler(w) {
+		return
+	}
+	// rejected before Event invokes are queued, like Lambda does
+	clientContext, valid := invokeClientContext(r)
+	if !valid {
+		writeInvokeAPIError(w, http.StatusBadRequest, invalidClientContextMessage)
+		return
+	}
+	identity, valid := invokeCognitoIdentity(r)
+	if !valid {
+		writeInvokeAPIError(w, http.StatusBadRequest, invalidCognitoIdentityMessage)
+		return
+	}
+	if handleInvocationType(w, r, sandbox, bs) {
+		return
+	}
+	bodyBytes, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		log.Warnf("Rejected invoke, the payload exceeds the %d bytes limit", maxBytesErr.Limit)
+		writeInvokeAPIError(w, http.StatusRequestEntityTooLarge, payloadTooLargeMessage())
+		return
+	}
 	if err != nil {
 		log.Errorf("Failed to read invoke body: %s", err)
-		w.WriteHeader(500)
+		writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
 		return
 	}
 
-	initDuration := ""
-	inv := GetenvWithDefault("AWS_LAMBDA_FUNCTION_TIMEOUT", "300")
-	timeoutDuration, _ := time.ParseDuration(inv + "s")
-	// Default
-	timeout, err := strconv.ParseInt(inv, 10, 64)
+	// warm invokes report an Init Duration of 0, so that REPORT lines always have the same fields
+	var initDurationMs float64
+	timeoutDuration, err := functionTimeout()
 	if err != nil {
 		panic(err)
 	}
@@ -176,94 +336,297 @@ func InvokeHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs i
 	functionVersion := GetenvWithDefault("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST")
 	memorySize := GetenvWithDefault("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "3008")
 
-	if !initDone {
-
-		initStart, initEnd := InitHandler(sandbox, functionVersion, timeout, bs)
+	initMutex.Lock()
+	coldStart := !initDone
+	if coldStart {
 
-		// Calculate InitDuration
-		initTimeMS := math.Min(float64(initEnd.Sub(initStart).Nanoseconds()),
-			float64(timeoutDuration.Nanoseconds())) / float64(time.Millisecond)
+		initStart, initEnd := InitHandler(sandbox, functionVersion, timeoutDuration.Milliseconds(), bs)
 
-		initDuration = fmt.Sprintf("Init Duration: %.2f ms\t", initTimeMS)
+		// Calculate InitDuration, replaced by the one rapid measures once init completes
+		initDurationMs = math.Min(float64(initEnd.Sub(initStart).Nanoseconds()),
+			float64(initTimeout().Nanoseconds())) / float64(time.Millisecond)
 
 		// Set initDone so next invokes do not try to Init the function again
 		initDone = true
 	}
+	initMutex.Unlock()
 
 	invokeStart := time.Now()
+	invokeID := uuid.New().String()
+	attributeLogs(r.Context(), invokeID)
 	invokePayload := &interop.Invoke{
-		ID:                 uuid.New().String(),
-		InvokedFunctionArn: fmt.Sprintf("arn:aws:lambda:us-east-1:012345678912:function:%s", GetenvWithDefault("AWS_LAMBDA_FUNCTION_NAME", "test_function")),
-		TraceID:            r.Header.Get("X-Amzn-Trace-Id"),
-		LambdaSegmentID:    r.Header.Get("X-Amzn-Segment-Id"),
-		Payload:            bytes.NewReader(bodyBytes),
+		ID:                    invokeID,
+		InvokedFunctionArn:    functionArn(),
+		TraceID:               invokeTraceID(r.Header.Get(traceIDHeader), invokeID),
+		LambdaSegmentID:       r.Header.Get("X-Amzn-Segment-Id"),
+		ClientContext:         clientContext,
+		CognitoIdentityID:     identity.CognitoIdentityID,
+		CognitoIdentityPoolID: identity.CognitoIdentityPoolID,
+		Payload:               bytes.NewReader(bodyBytes),
+	}
+	// the REPORT line and the timeout error then reflect the timeout of this invoke
+	if timeout, overridden := requestTimeout(r); overridden {
+		timeoutDuration = timeout
+		invokePayload.DeadlineNs = strconv.FormatInt(metering.Monotime()+timeout.Nanoseconds(), 10)
+	}
+	// the readiness canary is neither reported nor written to the response sink
+	counted := !isUncountedInvoke(r.Context())
+	if counted {
+		printStart(invokePayload.ID, functionVersion)
+	}
+	endReports := func(status string) int64 {
+		if !counted {
+			platformEvents.takeReportSpans(invokePayload.ID)
+			return int64(math.Ceil(invokeDurationMs(invokeStart, timeoutDuration)))
+		}
+		return printEndReports(invokePayload.ID, initDurationMs, memorySize, invokeStart, timeoutDuration, status, reportedTags(r))
+	}
+	w.Header().Set(requestIDHeader, invokePayload.ID)
+	w.Header().Set(executedRuntimeHeader, functionRuntime())
+	if invokePayload.TraceID != "" {
+		w.Header().Set(traceIDHeader, invokePayload.TraceID)
 	}
-	fmt.Println("START RequestId: " + invokePayload.ID + " Version: " + functionVersion)
 
-	// If we write to 'w' directly and waitUntilRelease fails, we won't be able to propagate error anymore
+	// If we write to 'w' directly and waitUntilRelease fails, we won't be able to propagate error anymore.
+	// Streamed function responses are the exception, they are sent to 'w' as they are produced.
 	invokeResp := &ResponseWriterProxy{}
-	if err := sandbox.Invoke(invokeResp, invokePayload); err != nil {
+	if _, canFlush := w.(http.Flusher); canFlush {
+		invokeResp.stream = w
+		invokeResp.keepStreamed = GetenvWithDefault(responseSinkEnvKey, "") != ""
+	}
+	err = invokeRecovering(sandbox, invokeResp, invokePayload)
+	if err != rapidcore.ErrAlreadyReserved {
+		initDurationMs, invokeStart = measuredInitDuration(initDurationMs, invokeStart, coldStart)
+	}
+	if failed := initFailures.report(invokeResp.Body); err != rapidcore.ErrAlreadyReserved {
+		setInitFailed(failed || err == rapidcore.ErrInitDoneFailed || errors.Is(err, rapidcore.ErrInitTimeout))
+	}
+	if invokeResp.Streamed {
+		status := reportStatusSuccess
+		if err != nil {
+			log.Errorf("Streamed response of %s was interrupted: %s", invokePayload.ID, err)
+			status = reportStatusError
+		}
+		if invokeResp.clientGone {
+			log.Debugf("The client of %s disconnected before the end of its streamed response", invokePayload.ID)
+		}
+		if invokeResp.truncated {
+			status = reportStatusError
+		}
+		if invokeResp.keepStreamed && counted {
+			sinkResponse(invokeResp.Body)
+		}
+		endReports(status)
+		return
+	}
+	if errors.Is(err, errInvokePanicked) {
+		setBilledDurationHeader(w, endReports(reportStatusError))
+		writeInvokePanic(w, err)
+		return
+	}
+	if errors.Is(err, rapidcore.ErrInitTimeout) {
+		log.Error(err)
+		setBilledDurationHeader(w, endReports(reportStatusTimeout))
+		w.Header().Set(functionErrorHeader, functionErrorUnhandled)
+		writeJSONError(w, functionErrorStatus(), string(fatalerror.SandboxTimeout), err.Error())
+		return
+	}
+	if err != nil {
 		switch err {
 
 		// Reserve errors:
 		case rapidcore.ErrAlreadyReserved:
-			log.Errorf("Failed to reserve: %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			log.Warnf("Rejected invoke %s, the sandbox is busy with another invoke", invokePayload.ID)
+			writeSandboxBusy(w)
 			return
 		case rapidcore.ErrInternalServerError:
-			w.WriteHeader(http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, serviceErrorType, err.Error())
 			return
 		case rapidcore.ErrInitDoneFailed:
-			w.WriteHeader(http.StatusBadGateway)
-			w.Write(invokeResp.Body)
+			writeFilteredFunctionError(w, r.Context(), invokePayload.ID, invokeResp.Body)
 			return
 		case rapidcore.ErrReserveReservationDone:
 			// TODO use http.StatusBadGateway
-			w.WriteHeader(http.StatusGatewayTimeout)
+			writeJSONError(w, http.StatusGatewayTimeout, serviceErrorType, err.Error())
 			return
 
 		// Invoke errors:
-		case rapidcore.ErrNotReserved:
-		case rapidcore.ErrAlreadyReplied:
-		case rapidcore.ErrAlreadyInvocating:
+		case rapidcore.ErrNotReserved, rapidcore.ErrAlreadyReplied, rapidcore.ErrAlreadyInvocating:
 			log.Errorf("Failed to set reply stream: %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, serviceErrorType, err.Error())
 			return
 		case rapidcore.ErrInvokeReservationDone:
 			// TODO use http.StatusBadGateway
-			w.WriteHeader(http.StatusGatewayTimeout)
+			writeJSONError(w, http.StatusGatewayTimeout, serviceErrorType, err.Error())
 			return
 		case rapidcore.ErrInvokeResponseAlreadyWritten:
 			return
 		// AwaitRelease errors:
 		case rapidcore.ErrInvokeDoneFailed:
-			w.WriteHeader(http.StatusBadGateway)
-			w.Write(invokeResp.Body)
+			writeFilteredFunctionError(w, r.Context(), invokePayload.ID, invokeResp.Body)
 			return
 		case rapidcore.ErrReleaseReservationDone:
 			// TODO return sandbox status when we implement async reset handling
 			// TODO use http.StatusOK
-			w.WriteHeader(http.StatusGatewayTimeout)
+			writeJSONError(w, http.StatusGatewayTimeout, serviceErrorType, err.Error())
 			return
 		case rapidcore.ErrInvokeTimeout:
-			printEndReports(invokePayload.ID, initDuration, memorySize, invokeStart, timeoutDuration)
+			setBilledDurationHeader(w, endReports(reportStatusTimeout))
 
-			w.Write([]byte(fmt.Sprintf("Task timed out after %d.00 seconds", timeout)))
-			time.Sleep(100 * time.Millisecond)
-			//initDone = false
+			// unlike a handler that returned nothing, a timeout is always a function error
+			message := fmt.Sprintf("Task timed out after %.2f seconds", timeoutDuration.Seconds())
+			log.Warnf("Invoke %s: %s", invokePayload.ID, message)
+			w.Header().Set(functionErrorHeader, functionErrorUnhandled)
+			writeJSONError(w, functionErrorStatus(), functionTimeoutErrorType, message)
 			return
 		}
 	}
 
-	printEndReports(invokePayload.ID, initDuration, memorySize, invokeStart, timeoutDuration)
+	if int64(len(invokeResp.Body)) > maxResponseBytes {
+		setBilledDurationHeader(w, endReports(reportStatusError))
+		writeResponseTooLarge(w, invokePayload.ID, len(invokeResp.Body))
+		return
+	}
+
+	status := reportStatusSuccess
+	if invokeResp.Header().Get(directinvoke.ErrorTypeHeader) != "" {
+		status = reportStatusError
+	}
+	setBilledDurationHeader(w, endReports(status))
+	if counted {
+		sinkResponse(invokeResp.Body)
+	}
 
+	if status == reportStatusError {
+		// the runtime reported a function error through /invocation/{id}/error
+		writeFilteredFunctionError(w, r.Context(), invokePayload.ID, invokeResp.Body)
+		return
+	}
+
+	body, err := filterResponse(r.Context(), invokePayload.ID, invokeResp.Body)
+	if err != nil {
+		writeResponseFilterError(w, invokePayload.ID, err)
+		return
+	}
 	if invokeResp.StatusCode != 0 {
 		w.WriteHeader(invokeResp.StatusCode)
 	}
-	w.Write(invokeResp.Body)
+	if _, err := w.Write(body); err != nil {
+		log.Debugf("The client of %s disconnected before its response was sent: %s", invokePayload.ID, err)
+	}
+}
+
+// functionErrorStatus returns the HTTP status used for function errors. Lambda's Invoke API
+// answers function errors with 200 and the X-Amz-Function-Error header, which is the default.
+func functionErrorStatus() int {
+	value := GetenvWithDefault(functionErrorStatusEnvKey, strconv.Itoa(defaultFunctionErrorStatus))
+	status, err := strconv.Atoi(value)
+	if err != nil || status < 200 || status > 599 {
+		log.Warnf("Invalid %s %q, using %d", functionErrorStatusEnvKey, value, defaultFunctionErrorStatus)
+		return defaultFunctionErrorStatus
+	}
+	return status
+}
+
+// writeResponseTooLarge answers in place of a response larger than maxResponseBytes, with the
+// error Lambda reports for oversized responses
+func writeResponseTooLarge(w http.ResponseWriter, invokeID string, size int) {
+	tooLarge := interop.ErrorResponseTooLarge{ResponseSize: size, MaxResponseSize: int(maxResponseBytes)}
+	log.Warnf("Invoke %s: %s", invokeID, tooLarge.Error())
+	w.Header().Set(functionErrorHeader, functionErrorUnhandled)
+	writeJSONError(w, http.StatusBadGateway, string(fatalerror.FunctionOversizedResponse), tooLarge.Error())
 }
 
-func InitHandler(sandbox Sandbox, functionVersion string, timeout int64, bs interop.Bootstrap) (time.Time, time.Time) {
+func writeFunctionError(w http.ResponseWriter, body []byte) {
+	w.Header().Set(functionErrorHeader, functionErrorUnhandled)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(functionErrorStatus())
+	w.Write(body)
+}
+
+// functionTimezone is the TZ of the function, Lambda's :UTC unless AWS_LAMBDA_RIE_TZ is set
+func functionTimezone() string {
+	return GetenvWithDefault(timezoneEnvKey, defaultTimezone)
+}
+
+// functionRuntime is the runtime identifier configured with AWS_LAMBDA_RIE_RUNTIME, e.g. nodejs18.x
+func functionRuntime() string {
+	return GetenvWithDefault(runtimeEnvKey, defaultRuntime)
+}
+
+// executionEnv is the AWS_EXECUTION_ENV Lambda sets for the runtime, e.g. AWS_Lambda_nodejs18.x
+func executionEnv() string {
+	return "AWS_Lambda_" + functionRuntime()
+}
+
+// warnSlowInit logs a warning when the cold start took longer than AWS_LAMBDA_RIE_INIT_WARN_MS
+func warnSlowInit(initTimeMS float64) {
+	value := GetenvWithDefault(initWarnMsEnvKey, "")
+	if value == "" {
+		return
+	}
+
+	thresholdMS, err := strconv.ParseFloat(value, 64)
+	if err != nil || thresholdMS < 0 {
+		log.Warnf("Invalid %s %q, ignoring", initWarnMsEnvKey, value)
+		return
+	}
+
+	if initTimeMS > thresholdMS {
+		log.Warnf("SLOW COLD START: Init Duration %.2f ms exceeded the %s threshold of %.0f ms", initTimeMS, initWarnMsEnvKey, thresholdMS)
+	}
+}
+
+// warnSlowInvoke logs a warning when the invoke took longer than AWS_LAMBDA_RIE_SLOW_INVOKE_MS
+func warnSlowInvoke(invokeID string, durationMS float64) {
+	value := GetenvWithDefault(slowInvokeMsEnvKey, "")
+	if value == "" {
+		return
+	}
+
+	thresholdMS, err := strconv.ParseFloat(value, 64)
+	if err != nil || thresholdMS < 0 {
+		log.Warnf("Invalid %s %q, ignoring", slowInvokeMsEnvKey, value)
+		return
+	}
+
+	if durationMS > thresholdMS {
+		log.Warnf("SLOW INVOKE: RequestId %s Duration %.2f ms exceeded the %s threshold of %.0f ms", invokeID, durationMS, slowInvokeMsEnvKey, thresholdMS)
+	}
+}
+
+// reservedEnvKeys are the runtime variables Lambda reserves that the emulator sets, other than the ones
+// that configure the emulator itself (function name, version, memory size and region)
+// see https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html#configuration-envvars-runtime
+var reservedEnvKeys = map[string]struct{}{
+	"AWS_DEFAULT_REGION":             {},
+	"AWS_LAMBDA_INITIALIZATION_TYPE": {},
+	"AWS_LAMBDA_LOG_GROUP_NAME":      {},
+	"AWS_LAMBDA_LOG_STREAM_NAME":     {},
+	"LAMBDA_TASK_ROOT":               {},
+	"LAMBDA_RUNTIME_DIR":             {},
+}
+
+func isReservedEnv(key string) bool {
+	_, reserved := reservedEnvKeys[key]
+	return reserved
+}
+
+// warnShadowedReservedEnv explains why the function sees other values than in Lambda for the reserved
+// variables set in the container, or that they were dropped in strict mode
+func warnShadowedReservedEnv(shadowed []string, strict bool) {
+	if len(shadowed) == 0 {
+		return
+	}
+	sort.Strings(shadowed)
+	if strict {
+		log.Warnf("Not forwarding %s from the environment, Lambda reserves them", strings.Join(shadowed, ", "))
+		return
+	}
+	log.Warnf("%s from the environment shadow the values Lambda reserves, set %s=true to keep Lambda's", strings.Join(shadowed, ", "), strictReservedEnvKey)
+}
+
+func InitHandler(sandbox Sandbox, functionVersion string, timeoutMs int64, bs interop.Bootstrap) (time.Time, time.Time) {
 	additionalFunctionEnvironmentVariables := map[string]string{}
 
 	// Add default Env Vars if they were not defined. This is a required otherwise 1p Python2.7, Python3.6, and
@@ -272,35 +635,88 @@ func InitHandler(sandbox Sandbox, functionVersion string, timeout int64, bs inte
 	additionalFunctionEnvironmentVariables["AWS_LAMBDA_LOG_STREAM_NAME"] = "$LATEST"
 	additionalFunctionEnvironmentVariables["AWS_LAMBDA_FUNCTION_VERSION"] = "$LATEST"
 	additionalFunctionEnvironmentVariables["AWS_LAMBDA_FUNCTION_MEMORY_SIZE"] = "3008"
-	additionalFunctionEnvironmentVariables["AWS_LAMBDA_FUNCTION_NAME"] = "test_function"
+	additionalFunctionEnvironmentVariables["AWS_LAMBDA_FUNCTION_NAME"] = functionName()
+
+	// The rest of the runtime environment Lambda sets, so that handlers relying on them (e.g. on TZ for dates)
+	// behave as in production, see https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html#configuration-envvars-runtime
+	additionalFunctionEnvironmentVariables["AWS_REGION"] = functionRegion()
+	additionalFunctionEnvironmentVariables["AWS_DEFAULT_REGION"] = functionRegion()
+	additionalFunctionEnvironmentVariables["AWS_LAMBDA_INITIALIZATION_TYPE"] = "on-demand"
+	additionalFunctionEnvironmentVariables["LAMBDA_TASK_ROOT"] = "/var/task"
+	additionalFunctionEnvironmentVariables["LAMBDA_RUNTIME_DIR"] = "/var/runtime"
+	additionalFunctionEnvironmentVariables["LANG"] = "en_US.UTF-8"
+	additionalFunctionEnvironmentVariables["PATH"] = "/var/lang/bin:/usr/local/bin:/usr/bin/:/bin:/opt/bin"
+	additionalFunctionEnvironmentVariables["LD_LIBRARY_PATH"] = "/var/lang/lib:/lib64:/usr/lib64:/var/runtime:/var/runtime/lib:/var/task:/var/task/lib:/opt/lib"
+	if platformLogFormat == logFormatJSON {
+		additionalFunctionEnvironmentVariables[logFormatEnvKey] = "JSON"
+	}
 
 	// Forward Env Vars from the running system (container) to what the function can view. Without this, Env Vars will
 	// not be viewable when the function runs.
+	strictReserved := GetenvWithDefault(strictReservedEnvKey, "false") == "true"
+	var shadowed []string
 	for _, env := range os.Environ() {
 		// Split the env into by the first "=". This will account for if the env var's value has a '=' in it
 		envVar := strings.SplitN(env, "=", 2)
+		if reserved, found := additionalFunctionEnvironmentVariables[envVar[0]]; found && reserved != envVar[1] && isReservedEnv(envVar[0]) {
+			shadowed = append(shadowed, envVar[0])
+			if strictReserved {
+				continue
+			}
+		}
 		additionalFunctionEnvironmentVariables[envVar[0]] = envVar[1]
 	}
+	warnShadowedReservedEnv(shadowed, strictReserved)
+	// the function must talk to the emulator's own Runtime API, which rapid sets on exec
+	if external, found := additionalFunctionEnvironmentVariables[runtimeAPIEnvKey]; found {
+		log.Warnf("Ignoring %s=%s from the environment, the function uses the emulator's Runtime API", runtimeAPIEnvKey, external)
+		delete(additionalFunctionEnvironmentVariables, runtimeAPIEnvKey)
+	}
+
+	if tracePropagationEnabled() {
+		// runtimes overwrite _X_AMZN_TRACE_ID on every invoke, this one covers calls made during init
+		if _, found := additionalFunctionEnvironmentVariables[traceIDEnvKey]; !found {
+			additionalFunctionEnvironmentVariables[traceIDEnvKey] = newTraceID(uuid.New().String())
+		}
+	}
+
+	environment := env.NewEnvironment()
+	// TZ is reserved, unlike the defaults above a TZ of the container does not apply to the function
+	timezone := functionTimezone()
+	if hostTimezone, found := os.LookupEnv("TZ"); found && hostTimezone != timezone {
+		log.Infof("The function runs with TZ=%s as in Lambda rather than the container's TZ=%s, set %s to change it", timezone, hostTimezone, timezoneEnvKey)
+	}
+	environment.SetTimezone(timezone)
+	// an AWS_EXECUTION_ENV set in the container, as AWS base images do, is kept
+	if environment.GetExecutionEnv() == "" {
+		environment.SetExecutionEnv(executionEnv())
+	}
+
+	// resolved again rather than left to rapid, which would let the environment override a positional handler
+	handler, _, _ := resolveHandler(positionalHandler)
+
+	runtime := runtimeInfo(environment.GetExecutionEnv())
+	name := functionName()
+	printInitStart(runtime, name, functionVersion)
 
 	initStart := time.Now()
 	// pass to rapid
 	sandbox.Init(&interop.Init{
-		Handler:           GetenvWithDefault("AWS_LAMBDA_FUNCTION_HANDLER", os.Getenv("_HANDLER")),
-		AwsKey:            os.Getenv("AWS_ACCESS_KEY_ID"),
-		AwsSecret:         os.Getenv("AWS_SECRET_ACCESS_KEY"),
-		AwsSession:        os.Getenv("AWS_SESSION_TOKEN"),
-		XRayDaemonAddress: "0.0.0.0:0", // TODO
-		FunctionName:      GetenvWithDefault("AWS_LAMBDA_FUNCTION_NAME", "test_function"),
-		FunctionVersion:   functionVersion,
-		RuntimeInfo: interop.RuntimeInfo{
-			ImageJSON: "{}",
-			Arn:       "",
-			Version:   ""},
+		AccountID:                    functionAccountID(),
+		Handler:                      handler,
+		AwsKey:                       os.Getenv("AWS_ACCESS_KEY_ID"),
+		AwsSecret:                    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AwsSession:                   os.Getenv("AWS_SESSION_TOKEN"),
+		XRayDaemonAddress:            "0.0.0.0:0", // unused, the emulator itself never sends segments to a daemon
+		FunctionName:                 name,
+		FunctionVersion:              functionVersion,
+		InitTimeoutMs:                initTimeout().Milliseconds(),
+		RuntimeInfo:                  runtime,
 		CustomerEnvironmentVariables: additionalFunctionEnvironmentVariables,
 		SandboxType:                  interop.SandboxClassic,
 		Bootstrap:                    bs,
-		EnvironmentVariables:         env.NewEnvironment(),
-	}, timeout*1000)
+		EnvironmentVariables:         environment,
+	}, timeoutMs)
 	initEnd := time.Now()
 	return initStart, initEnd
 }
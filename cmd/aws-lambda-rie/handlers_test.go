@@ -0,0 +1,1000 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.amzn.com/lambda/core/directinvoke"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSandbox replaces the rapidcore emulator API, the invoke behaviour is provided by each test
+type mockSandbox struct {
+	initCalls int
+	lastInit  *interop.Init
+	invoke    func(w http.ResponseWriter, i *interop.Invoke) error
+}
+
+func (s *mockSandbox) Init(i *interop.Init, invokeTimeoutMs int64) {
+	s.initCalls++
+	s.lastInit = i
+}
+
+func (s *mockSandbox) Invoke(w http.ResponseWriter, i *interop.Invoke) error {
+	return s.invoke(w, i)
+}
+
+func respondWith(body string) func(w http.ResponseWriter, i *interop.Invoke) error {
+	return func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Write([]byte(body))
+		return nil
+	}
+}
+
+func respondWithFunctionError(body string) func(w http.ResponseWriter, i *interop.Invoke) error {
+	return func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Header().Set(directinvoke.ErrorTypeHeader, "Exception")
+		w.Write([]byte(body))
+		return nil
+	}
+}
+
+func invoke(t *testing.T, sandbox Sandbox, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+
+	w := httptest.NewRecorder()
+	InvokeHandler(w, req, sandbox, NewSimpleBootstrap([]string{}, ""))
+	return w
+}
+
+// directInvoke routes the request through chi so that the wildcard path parameter is populated
+func directInvoke(t *testing.T, sandbox Sandbox, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+
+	router := chi.NewRouter()
+	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+		DirectInvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// captureEvent makes the sandbox decode the event it receives into event
+func captureEvent(event interface{}) func(w http.ResponseWriter, i *interop.Invoke) error {
+	return func(w http.ResponseWriter, i *interop.Invoke) error {
+		if err := json.NewDecoder(i.Payload).Decode(event); err != nil {
+			return err
+		}
+		w.Write([]byte(`"ok"`))
+		return nil
+	}
+}
+
+func newInvokeRequest(body string) *http.Request {
+	return httptest.NewRequest("POST", "/2015-03-31/functions/function/invocations", strings.NewReader(body))
+}
+
+func TestInvokeHandlerSuccess(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"ok"`, w.Body.String())
+	assert.Empty(t, w.Header().Get(functionErrorHeader))
+	assert.NotEmpty(t, w.Header().Get(requestIDHeader))
+	assert.Equal(t, 1, sandbox.initCalls)
+}
+
+func TestInvokeHandlerFunctionErrorDefaultsToOK(t *testing.T) {
+	errorBody := `{"errorMessage": "Raising an exception", "errorType": "Exception"}`
+	sandbox := &mockSandbox{invoke: respondWithFunctionError(errorBody)}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+	assert.Equal(t, errorBody, w.Body.String())
+}
+
+func TestInvokeHandlerFunctionErrorConfiguredStatus(t *testing.T) {
+	t.Setenv(functionErrorStatusEnvKey, "502")
+	sandbox := &mockSandbox{invoke: respondWithFunctionError(`{}`)}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+}
+
+func TestInvokeHandlerRuntimeCrashIsFunctionError(t *testing.T) {
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Write([]byte(`{"errorType": "Runtime.ExitError"}`))
+		return rapidcore.ErrInvokeDoneFailed
+	}}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+	body, _ := io.ReadAll(w.Body)
+	assert.Equal(t, `{"errorType": "Runtime.ExitError"}`, string(body))
+}
+
+func TestDirectInvokeEventAccountMatchesArn(t *testing.T) {
+	t.Setenv(accountIDEnvKey, "210987654321")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	var event AwsFunctionRequestPayload
+	var invokedArn string
+	capture := captureEvent(&event)
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		invokedArn = i.InvokedFunctionArn
+		return capture(w, i)
+	}}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader("{}")))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "arn:aws:lambda:eu-west-1:210987654321:function:test_function", invokedArn)
+	assert.Equal(t, strings.Split(invokedArn, ":")[4], event.RequestContext.AccountID)
+	assert.Equal(t, "210987654321", sandbox.lastInit.AccountID)
+}
+
+func TestDirectInvokeRejectsDisallowedMethod(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("TRACE", "/hello", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.JSONEq(t, `{"Message": "Method Not Allowed"}`, w.Body.String())
+	assert.Contains(t, w.Header().Get("Allow"), "POST")
+	assert.Equal(t, 0, sandbox.initCalls)
+}
+
+func TestDirectInvokeConfiguredAllowedMethods(t *testing.T) {
+	t.Setenv(allowedMethodsEnvKey, "POST")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil)).Code)
+	assert.Equal(t, http.StatusOK, directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", nil)).Code)
+}
+
+func TestDirectInvokeAllowedMethodsPerFormat(t *testing.T) {
+	t.Setenv(allowedMethodsEnvKey, "POST")
+	t.Setenv(allowedMethodsEnvKey+"_FUNCTION_URL", "GET, DELETE")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "the format's variable overrides the one of all formats")
+	assert.Equal(t, "GET, DELETE", w.Header().Get("Allow"))
+	assert.Equal(t, http.StatusOK, directInvoke(t, sandbox, httptest.NewRequest("DELETE", "/hello", nil)).Code)
+
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Header.Set(eventFormatHeader, "apigw-rest")
+	assert.Equal(t, http.StatusMethodNotAllowed, directInvoke(t, sandbox, req).Code, "other formats use the variable of all formats")
+}
+
+func TestInvokeHandlerGeneratesTraceID(t *testing.T) {
+	t.Setenv(tracePropagationEnvKey, "true")
+	var traceID string
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		traceID = i.TraceID
+		return respondWith(`"ok"`)(w, i)
+	}}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+
+	requestID := strings.ReplaceAll(w.Header().Get(requestIDHeader), "-", "")
+	assert.Regexp(t, `^Root=1-[0-9a-f]{8}-[0-9a-f]{24};Parent=`+requestID[:16]+`;Sampled=0$`, traceID)
+	assert.Equal(t, traceID, w.Header().Get(traceIDHeader))
+	assert.NotEmpty(t, sandbox.lastInit.CustomerEnvironmentVariables[traceIDEnvKey])
+}
+
+func TestInvokeHandlerKeepsClientTraceID(t *testing.T) {
+	t.Setenv(tracePropagationEnvKey, "true")
+	clientTraceID := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+	var traceID string
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		traceID = i.TraceID
+		return respondWith(`"ok"`)(w, i)
+	}}
+
+	req := newInvokeRequest("{}")
+	req.Header.Set(traceIDHeader, clientTraceID)
+	w := invoke(t, sandbox, req)
+
+	assert.Equal(t, clientTraceID, traceID)
+	assert.Equal(t, clientTraceID, w.Header().Get(traceIDHeader))
+}
+
+func TestInvokeHandlerClientContext(t *testing.T) {
+	var clientContext string
+	invoked := 0
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		invoked++
+		clientContext = i.ClientContext
+		return respondWith(`"ok"`)(w, i)
+	}}
+
+	req := newInvokeRequest("{}")
+	req.Header.Set(clientContextHeader, base64.StdEncoding.EncodeToString([]byte(`{"custom": {"k": "v"}}`)))
+	assert.Equal(t, http.StatusOK, invoke(t, sandbox, req).Code)
+	assert.Equal(t, `{"custom": {"k": "v"}}`, clientContext)
+
+	for _, header := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("{not json")), base64.StdEncoding.EncodeToString([]byte("null"))} {
+		req := newInvokeRequest("{}")
+		req.Header.Set(clientContextHeader, header)
+		req.Header.Set(invocationTypeHeader, invocationTypeEvent)
+		w := invoke(t, sandbox, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, header)
+		assert.JSONEq(t, `{"errorType": "InvalidRequestContentException", "errorMessage": "`+invalidClientContextMessage+`"}`, w.Body.String())
+	}
+	assert.Equal(t, 1, invoked)
+}
+
+func TestInvokeHandlerCognitoIdentity(t *testing.T) {
+	var invoked *interop.Invoke
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		invoked = i
+		return respondWith(`"ok"`)(w, i)
+	}}
+
+	req := newInvokeRequest("{}")
+	req.Header.Set(cognitoIdentityHeader, `{"cognitoIdentityId": "us-east-1:1234", "cognitoIdentityPoolId": "us-east-1:pool"}`)
+	assert.Equal(t, http.StatusOK, invoke(t, sandbox, req).Code)
+	assert.Equal(t, "us-east-1:1234", invoked.CognitoIdentityID)
+	assert.Equal(t, "us-east-1:pool", invoked.CognitoIdentityPoolID)
+
+	invoked = nil
+	for _, header := range []string{"{not json", "null", `["us-east-1:1234"]`} {
+		req := newInvokeRequest("{}")
+		req.Header.Set(cognitoIdentityHeader, header)
+		w := invoke(t, sandbox, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, header)
+		assert.JSONEq(t, `{"errorType": "InvalidRequestContentException", "errorMessage": "`+invalidCognitoIdentityMessage+`"}`, w.Body.String())
+	}
+	assert.Nil(t, invoked)
+}
+
+func TestWarnSlowInit(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	warnSlowInit(5000)
+	assert.Empty(t, logs.String(), "no warning without a threshold")
+
+	t.Setenv(initWarnMsEnvKey, "1000")
+	warnSlowInit(500)
+	assert.Empty(t, logs.String())
+
+	warnSlowInit(1500.5)
+	assert.Contains(t, logs.String(), "Init Duration 1500.50 ms exceeded")
+}
+
+func TestWarnSlowInvoke(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	platformLog = io.Discard
+	t.Cleanup(func() { platformLog = os.Stdout })
+
+	report := func(elapsed time.Duration) {
+		printEndReports("request-id", 0, "128", time.Now().Add(-elapsed), time.Second, reportStatusSuccess, "")
+	}
+
+	report(500 * time.Millisecond)
+	assert.Empty(t, logs.String(), "no warning without a threshold")
+
+	t.Setenv(slowInvokeMsEnvKey, "200")
+	report(100 * time.Millisecond)
+	assert.Empty(t, logs.String())
+
+	report(2 * time.Second)
+	assert.Empty(t, logs.String(), "timeouts are not slow invokes")
+
+	report(500 * time.Millisecond)
+	assert.Regexp(t, `SLOW INVOKE: RequestId request-id Duration 5\d\d\.\d\d ms exceeded the AWS_LAMBDA_RIE_SLOW_INVOKE_MS threshold of 200 ms`, logs.String())
+}
+
+func TestDirectInvokeResponseEnvelope(t *testing.T) {
+	envelope := `{"statusCode": 201, "headers": {"X-Custom": "value"}, "body": "aGVsbG8=", "isBase64Encoded": true}`
+
+	t.Run("auto unwraps an envelope", func(t *testing.T) {
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(envelope)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "value", w.Header().Get("X-Custom"))
+		assert.NotEmpty(t, w.Header().Get(requestIDHeader))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("cookies are sent as separate Set-Cookie headers", func(t *testing.T) {
+		withCookies := `{"statusCode": 200, "headers": {"Set-Cookie": "header=1"}, "cookies": ["a=1; Path=/; HttpOnly", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "c=x,y"]}`
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(withCookies)}, httptest.NewRequest("GET", "/hello", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"header=1", "a=1; Path=/; HttpOnly", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "c=x,y"}, w.Header().Values("Set-Cookie"))
+	})
+
+	t.Run("auto passes other responses through", func(t *testing.T) {
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(`{"message": "hi"}`)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"message": "hi"}`, w.Body.String())
+	})
+
+	t.Run("always rejects responses without an envelope", func(t *testing.T) {
+		t.Setenv(responseEnvelopeEnvKey, responseEnvelopeAlways)
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(`"raw"`)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.JSONEq(t, `{"Message": "Internal Server Error"}`, w.Body.String())
+	})
+
+	t.Run("never returns the raw bytes", func(t *testing.T) {
+		t.Setenv(responseEnvelopeEnvKey, responseEnvelopeNever)
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(envelope)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, envelope, w.Body.String())
+	})
+
+	t.Run("function errors are not unwrapped", func(t *testing.T) {
+		w := directInvoke(t, &mockSandbox{invoke: respondWithFunctionError(`{"errorType": "Exception"}`)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+		assert.Equal(t, `{"errorType": "Exception"}`, w.Body.String())
+	})
+
+	t.Run("invalid UTF-8 bodies are rejected", func(t *testing.T) {
+		invalid := "{\"statusCode\": 200, \"body\": \"\xff\xfe\"}"
+		w := directInvoke(t, &mockSandbox{invoke: respondWith(invalid)}, httptest.NewRequest("POST", "/hello", nil))
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Contains(t, w.Body.String(), "not valid UTF-8")
+
+		base64Body := `{"statusCode": 200, "body": "//4=", "isBase64Encoded": true}`
+		w = directInvoke(t, &mockSandbox{invoke: respondWith(base64Body)}, httptest.NewRequest("POST", "/hello", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []byte{0xff, 0xfe}, w.Body.Bytes())
+	})
+
+	t.Run("the invoke API returns the envelope as is", func(t *testing.T) {
+		w := invoke(t, &mockSandbox{invoke: respondWith(envelope)}, newInvokeRequest("{}"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-Custom"))
+		assert.Equal(t, envelope, w.Body.String())
+	})
+}
+
+func TestReplaceBodyResetsChunkedFraming(t *testing.T) {
+	req := httptest.NewRequest("POST", "/hello", strings.NewReader("chunked body"))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.Header.Set("Transfer-Encoding", "chunked")
+	req.Header.Set("Content-Length", "3")
+
+	event := []byte(`{"body": "synthesized"}`)
+	replaceBody(req, event)
+
+	assert.Equal(t, int64(len(event)), req.ContentLength)
+	assert.Empty(t, req.TransferEncoding)
+	assert.Empty(t, req.Header.Values("Transfer-Encoding"))
+	assert.Equal(t, []string{strconv.Itoa(len(event))}, req.Header.Values("Content-Length"))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, event, body)
+}
+
+func TestDirectInvokeDefaultAccept(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	sandbox := &mockSandbox{invoke: captureEvent(&event)}
+
+	directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+	_, found := event.Headers["accept"]
+	assert.False(t, found, "absent Accept stays absent by default")
+
+	t.Setenv(defaultAcceptEnvKey, "application/json")
+	event = AwsFunctionRequestPayload{}
+	directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+	assert.Equal(t, "application/json", event.Headers["accept"])
+
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Header.Set("Accept", "text/html")
+	event = AwsFunctionRequestPayload{}
+	directInvoke(t, sandbox, req)
+	assert.Equal(t, "text/html", event.Headers["accept"])
+}
+
+func TestDirectInvokeFunctionURLLowercasesHeaders(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Custom-Header", "value")
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+
+	directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+
+	assert.Equal(t, "application/json", event.Headers["content-type"])
+	assert.Equal(t, "value", event.Headers["x-custom-header"])
+	assert.Equal(t, "10.0.0.1, 10.0.0.2", event.Headers["x-forwarded-for"])
+	_, found := event.Headers["Content-Type"]
+	assert.False(t, found)
+}
+
+func TestDirectInvokeForwardsHost(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Host = "tenant-a.example.com"
+
+	directInvoke(t, &mockSandbox{invoke: captureEvent(&event)}, req)
+
+	assert.Equal(t, "tenant-a.example.com", event.Headers["host"])
+	assert.Equal(t, "tenant-a.example.com", event.RequestContext.DomainName)
+}
+
+func TestInitHandlerDropsExternalRuntimeAPI(t *testing.T) {
+	t.Setenv(runtimeAPIEnvKey, "external.example.com:9001")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	_, forwarded := sandbox.lastInit.CustomerEnvironmentVariables[runtimeAPIEnvKey]
+	assert.False(t, forwarded)
+}
+
+func TestInitHandlerSetsRuntimeEnvironmentDefaults(t *testing.T) {
+	t.Setenv("LANG", "C.UTF-8")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	customerEnv := sandbox.lastInit.CustomerEnvironmentVariables
+	assert.Equal(t, "/var/task", customerEnv["LAMBDA_TASK_ROOT"])
+	assert.Equal(t, "/var/runtime", customerEnv["LAMBDA_RUNTIME_DIR"])
+	assert.Equal(t, "on-demand", customerEnv["AWS_LAMBDA_INITIALIZATION_TYPE"])
+	assert.Equal(t, functionRegion(), customerEnv["AWS_REGION"])
+	assert.Equal(t, "C.UTF-8", customerEnv["LANG"], "values set in the container are kept")
+}
+
+func TestInitHandlerSetsTimezone(t *testing.T) {
+	functionTimezoneOf := func() string {
+		sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+		invoke(t, sandbox, newInvokeRequest("{}"))
+		environment := sandbox.lastInit.EnvironmentVariables
+		environment.StoreRuntimeAPIEnvironmentVariable("127.0.0.1:9001")
+		environment.StoreEnvironmentVariablesFromInit(sandbox.lastInit.CustomerEnvironmentVariables, "", "", "", "", "", "")
+		return environment.RuntimeExecEnv()["TZ"]
+	}
+
+	t.Setenv("TZ", "Europe/Paris")
+	assert.Equal(t, ":UTC", functionTimezoneOf(), "the container's TZ does not leak into the function")
+
+	t.Setenv(timezoneEnvKey, "America/New_York")
+	assert.Equal(t, "America/New_York", functionTimezoneOf())
+}
+
+// slowInitSandbox widens the window in which concurrent first invokes could both Init
+type slowInitSandbox struct {
+	initCalls atomic.Int32
+}
+
+func (s *slowInitSandbox) Init(i *interop.Init, invokeTimeoutMs int64) {
+	s.initCalls.Add(1)
+	time.Sleep(50 * time.Millisecond)
+}
+
+func (s *slowInitSandbox) Invoke(w http.ResponseWriter, i *interop.Invoke) error {
+	w.Write([]byte(`"ok"`))
+	return nil
+}
+
+func TestConcurrentFirstInvokesInitOnce(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	sandbox := &slowInitSandbox{}
+	bs := NewSimpleBootstrap([]string{}, "")
+
+	const invokes = 8
+	var wg sync.WaitGroup
+	codes := make(chan int, invokes)
+	for i := 0; i < invokes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			InvokeHandler(w, newInvokeRequest("{}"), sandbox, bs)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	assert.Equal(t, int32(1), sandbox.initCalls.Load())
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
+
+func TestInitHandlerSetsExecutionEnv(t *testing.T) {
+	executionEnvOf := func() string {
+		sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+		invoke(t, sandbox, newInvokeRequest("{}"))
+		return sandbox.lastInit.EnvironmentVariables.GetExecutionEnv()
+	}
+
+	t.Setenv("AWS_EXECUTION_ENV", "")
+	os.Unsetenv("AWS_EXECUTION_ENV")
+	assert.Equal(t, "AWS_Lambda_provided", executionEnvOf())
+
+	t.Setenv(runtimeEnvKey, "nodejs18.x")
+	assert.Equal(t, "AWS_Lambda_nodejs18.x", executionEnvOf())
+
+	t.Setenv("AWS_EXECUTION_ENV", "AWS_Lambda_python3.11")
+	assert.Equal(t, "AWS_Lambda_python3.11", executionEnvOf())
+}
+
+func TestInvokeResponseCarriesExecutedRuntime(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	assert.Equal(t, "provided", invoke(t, sandbox, newInvokeRequest("{}")).Header().Get(executedRuntimeHeader))
+
+	t.Setenv(runtimeEnvKey, "python3.11")
+	assert.Equal(t, "python3.11", invoke(t, sandbox, newInvokeRequest("{}")).Header().Get(executedRuntimeHeader))
+	assert.Equal(t, "python3.11", directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil)).Header().Get(executedRuntimeHeader))
+}
+
+func TestInvokeHandlerStreamsChunkedResponse(t *testing.T) {
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Header().Set(directinvoke.ContentTypeHeader, "text/plain")
+		w.Header().Set(directinvoke.FunctionResponseModeHeader, "streaming")
+		for _, chunk := range []string{"first ", "second"} {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		return nil
+	}}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/2015-03-31/functions/function/invocations", "application/json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.NotEmpty(t, resp.Header.Get(requestIDHeader))
+	assert.Equal(t, "first second", string(body))
+}
+
+func TestInvokeHandlerStreamedResponseLimitAndSink(t *testing.T) {
+	maxResponseBytes = 10
+	t.Cleanup(func() { maxResponseBytes = syncPayloadLimitBytes })
+	sinkPath := filepath.Join(t.TempDir(), "response")
+	t.Setenv(responseSinkEnvKey, sinkPath)
+	var platformLines bytes.Buffer
+	platformLog = &platformLines
+	t.Cleanup(func() { platformLog = os.Stdout })
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Header().Set(directinvoke.FunctionResponseModeHeader, "streaming")
+		for _, chunk := range []string{"first ", "next", " too much"} {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		return nil
+	}}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/2015-03-31/functions/function/invocations", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, "first next", string(body), "the stream stops at --max-response-bytes")
+	sunk, err := os.ReadFile(sinkPath)
+	require.NoError(t, err)
+	assert.Equal(t, "first next", string(sunk))
+	assert.Contains(t, platformLines.String(), "REPORT RequestId: ")
+}
+
+func TestInvokeHandlerClientDisconnectsMidStream(t *testing.T) {
+	disconnected := make(chan struct{})
+	writeErrors := 0
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Header().Set(directinvoke.FunctionResponseModeHeader, "streaming")
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-disconnected
+		// the runtime keeps streaming after the client is gone
+		chunk := bytes.Repeat([]byte("a"), 32*1024)
+		for n := 0; n < 1000 && !w.(*ResponseWriterProxy).clientGone; n++ {
+			if _, err := w.Write(chunk); err != nil {
+				writeErrors++
+			}
+			w.(http.Flusher).Flush()
+		}
+		assert.True(t, w.(*ResponseWriterProxy).clientGone)
+		return nil
+	}}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	var platformLines bytes.Buffer
+	platformLog = &platformLines
+	t.Cleanup(func() { platformLog = os.Stdout })
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/2015-03-31/functions/function/invocations", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	first := make([]byte, len("first"))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err)
+	resp.Body.Close()
+	close(disconnected)
+	<-served
+
+	assert.Equal(t, 0, writeErrors, "the runtime's writes succeed")
+	assert.Contains(t, platformLines.String(), "REPORT RequestId: ")
+	// the sandbox is released for the next invoke
+	sandbox.invoke = respondWith(`"ok"`)
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"ok"`, w.Body.String())
+}
+
+func TestInvokePayloadLimit(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := invoke(t, sandbox, newInvokeRequest(strings.Repeat("a", syncPayloadLimitBytes+1)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"errorType": "RequestTooLargeException", "errorMessage": "Request must be smaller than 6291456 bytes for the InvokeFunction operation"}`, w.Body.String())
+	assert.Equal(t, 0, sandbox.initCalls)
+	assert.Equal(t, http.StatusOK, invoke(t, sandbox, newInvokeRequest(strings.Repeat("a", syncPayloadLimitBytes))).Code)
+
+	maxPayloadBytes = 8
+	t.Cleanup(func() { maxPayloadBytes = syncPayloadLimitBytes })
+	assert.Equal(t, http.StatusOK, invoke(t, sandbox, newInvokeRequest(`"abcdef"`)).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, invoke(t, sandbox, newInvokeRequest(`"abcdefg"`)).Code)
+}
+
+func TestInvokeResponseLimit(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"` + strings.Repeat("a", syncPayloadLimitBytes) + `"`)}
+
+	w := invoke(t, sandbox, newInvokeRequest(`{}`))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "Unhandled", w.Header().Get("X-Amz-Function-Error"))
+	assert.JSONEq(t, `{"errorType": "Function.ResponseSizeTooLarge", "errorMessage": "Response payload size (6291458 bytes) exceeded maximum allowed payload size (6291456 bytes)."}`, w.Body.String())
+
+	maxResponseBytes = 8
+	t.Cleanup(func() { maxResponseBytes = syncPayloadLimitBytes })
+	sandbox.invoke = respondWith(`"abcdef"`)
+	assert.Equal(t, http.StatusOK, invoke(t, sandbox, newInvokeRequest(`{}`)).Code)
+	sandbox.invoke = respondWith(`"abcdefg"`)
+	assert.Equal(t, http.StatusBadGateway, invoke(t, sandbox, newInvokeRequest(`{}`)).Code)
+}
+
+func TestDirectInvokeEventLimit(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	maxPayloadBytes = 1024
+	t.Cleanup(func() { maxPayloadBytes = syncPayloadLimitBytes })
+
+	// within the request limit of Function URLs, but not once base64 encoded in the event
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader(strings.Repeat("a", 1000))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"Message": "Request Entity Too Large"}`, w.Body.String())
+	assert.Equal(t, 0, sandbox.initCalls)
+	assert.Equal(t, http.StatusOK, directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader("small"))).Code)
+}
+
+func TestDirectInvokeRequestLimit(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	tooLarge := strings.Repeat("a", syncPayloadLimitBytes+1)
+	w := directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader(tooLarge)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"Message": "Request Entity Too Large"}`, w.Body.String())
+	assert.Equal(t, 0, sandbox.initCalls)
+
+	t.Setenv(maxRequestBytesEnvKey, "4")
+	assert.Equal(t, http.StatusOK, directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader("abcd"))).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello", strings.NewReader("abcde"))).Code)
+}
+
+func TestDirectInvokeQueryBody(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	sandbox := &mockSandbox{invoke: captureEvent(&event)}
+	get := func(target string) AwsFunctionRequestPayload {
+		event = AwsFunctionRequestPayload{}
+		directInvoke(t, sandbox, httptest.NewRequest("GET", target, nil))
+		return event
+	}
+
+	event = get(`/hello?body=%7B%22a%22%3A1%7D&x=1`)
+	assert.Equal(t, "", event.Body, "the query body is ignored by default")
+	assert.Equal(t, `{"a":1}`, event.QueryStringParameters["body"])
+
+	t.Setenv(allowQueryBodyEnvKey, "true")
+	event = get(`/hello?body=%7B%22a%22%3A1%7D&x=1`)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(`{"a":1}`)), event.Body)
+	assert.Equal(t, map[string]string{"x": "1"}, event.QueryStringParameters)
+	assert.Equal(t, "x=1", event.RawQueryString)
+
+	event = AwsFunctionRequestPayload{}
+	directInvoke(t, sandbox, httptest.NewRequest("POST", "/hello?body=ignored", strings.NewReader("sent")))
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("sent")), event.Body)
+	assert.Equal(t, "ignored", event.QueryStringParameters["body"])
+}
+
+func TestDirectInvokeMultiValueQuery(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	sandbox := &mockSandbox{invoke: captureEvent(&event)}
+
+	directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello?a=1&a=2&b=3", nil))
+	assert.Equal(t, "a=1&a=2&b=3", event.RawQueryString)
+	assert.Equal(t, map[string]string{"a": "1,2", "b": "3"}, event.QueryStringParameters)
+
+	event = AwsFunctionRequestPayload{}
+	directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello?b=2&a=x%2Cy&b=1&flag", nil))
+	assert.Equal(t, map[string]string{"a": "x,y", "b": "2,1", "flag": ""}, event.QueryStringParameters, "values are decoded and keep their order")
+
+	event = AwsFunctionRequestPayload{}
+	directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+	encoded, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "queryStringParameters")
+	assert.Contains(t, string(encoded), `"rawQueryString":""`)
+}
+
+func TestDirectInvokeRejectsUnroutedPath(t *testing.T) {
+	var event AwsFunctionRequestPayload
+	sandbox := &mockSandbox{invoke: captureEvent(&event)}
+
+	directInvoke(t, sandbox, httptest.NewRequest("POST", "/a%2Fb/c", nil))
+	assert.Equal(t, "/a%2Fb/c", event.RawPath, "escaped paths are captured as routed")
+
+	router := chi.NewRouter()
+	router.Post("/hello", func(w http.ResponseWriter, r *http.Request) {
+		DirectInvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	})
+	for _, format := range []string{"function-url", "apigw-rest"} {
+		sandbox.initCalls = 0
+		req := httptest.NewRequest("POST", "/hello", nil)
+		req.Header.Set(eventFormatHeader, format)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, format)
+		assert.Contains(t, w.Body.String(), routingErrorType, format)
+		assert.Equal(t, 0, sandbox.initCalls, "the function is not invoked with / instead of /hello")
+	}
+
+	w := httptest.NewRecorder()
+	DirectInvokeHandler(w, httptest.NewRequest("POST", "/hello", nil), sandbox, NewSimpleBootstrap([]string{}, ""))
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "requests that bypass chi")
+}
+
+func TestInvokeTimeoutIsDistinctFromEmptyResponse(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_FUNCTION_TIMEOUT", "1")
+
+	timedOut := invoke(t, &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		return rapidcore.ErrInvokeTimeout
+	}}, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, timedOut.Code)
+	assert.Equal(t, functionErrorUnhandled, timedOut.Header().Get(functionErrorHeader))
+	assert.JSONEq(t, `{"errorType": "Sandbox.Timedout", "errorMessage": "Task timed out after 1.00 seconds"}`, timedOut.Body.String())
+
+	returnedNothing := invoke(t, &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		return nil
+	}}, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, returnedNothing.Code)
+	assert.Empty(t, returnedNothing.Header().Get(functionErrorHeader))
+	assert.Empty(t, returnedNothing.Body.String())
+
+	returnedNull := invoke(t, &mockSandbox{invoke: respondWith("null")}, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, returnedNull.Code)
+	assert.Empty(t, returnedNull.Header().Get(functionErrorHeader))
+	assert.Equal(t, "null", returnedNull.Body.String())
+}
+
+func TestInvokeHandlerErrorsAreJSON(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_FUNCTION_TIMEOUT", "1")
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{rapidcore.ErrAlreadyReserved, http.StatusTooManyRequests},
+		{rapidcore.ErrInternalServerError, http.StatusInternalServerError},
+		{rapidcore.ErrInitDoneFailed, http.StatusOK},
+		{rapidcore.ErrReserveReservationDone, http.StatusGatewayTimeout},
+		{rapidcore.ErrNotReserved, http.StatusBadRequest},
+		{rapidcore.ErrAlreadyReplied, http.StatusBadRequest},
+		{rapidcore.ErrAlreadyInvocating, http.StatusBadRequest},
+		{rapidcore.ErrInvokeReservationDone, http.StatusGatewayTimeout},
+		{rapidcore.ErrInvokeDoneFailed, http.StatusOK},
+		{rapidcore.ErrReleaseReservationDone, http.StatusGatewayTimeout},
+		{rapidcore.ErrInvokeTimeout, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			w := invoke(t, &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+				w.Write([]byte(`{"errorType": "Runtime.ExitError"}`))
+				return test.err
+			}}, newInvokeRequest("{}"))
+
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.True(t, json.Valid(w.Body.Bytes()), w.Body.String())
+		})
+	}
+
+	t.Run("function error", func(t *testing.T) {
+		w := invoke(t, &mockSandbox{invoke: respondWithFunctionError(`{"errorType": "Exception"}`)}, newInvokeRequest("{}"))
+		assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestReportLineFields(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+	t.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "128")
+	// without cgroups, Max Memory Used is the memory size
+	files := cgroupMemoryFiles
+	cgroupMemoryFiles = nil
+	t.Cleanup(func() { cgroupMemoryFiles = files })
+
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	bs := NewSimpleBootstrap([]string{}, "")
+	InvokeHandler(httptest.NewRecorder(), newInvokeRequest("{}"), sandbox, bs)
+	InvokeHandler(httptest.NewRecorder(), newInvokeRequest("{}"), sandbox, bs)
+
+	var reports []string
+	for _, line := range strings.Split(platform.String(), "\n") {
+		if strings.HasPrefix(line, "REPORT ") {
+			reports = append(reports, line)
+		}
+	}
+	assert.Len(t, reports, 2)
+
+	report := regexp.MustCompile(`^REPORT RequestId: [0-9a-f-]{36}\tInit Duration: (\d+\.\d{2}) ms\tDuration: \d+\.\d{2} ms\tBilled Duration: \d+ ms\tMemory Size: 128 MB\tMax Memory Used: 128 MB\t$`)
+	cold := report.FindStringSubmatch(reports[0])
+	assert.NotNil(t, cold, "cold start REPORT: %q", reports[0])
+	warm := report.FindStringSubmatch(reports[1])
+	assert.NotNil(t, warm, "warm REPORT: %q", reports[1])
+	if warm != nil {
+		assert.Equal(t, "0.00", warm[1])
+	}
+	assert.Equal(t, strings.Count(reports[0], "\t"), strings.Count(reports[1], "\t"))
+}
+
+func TestDirectInvokeCorrectsDeclaredContentLength(t *testing.T) {
+	lying := `{"statusCode": 200, "headers": {"Content-Length": "999"}, "body": "hi"}`
+	sandbox := &mockSandbox{invoke: respondWith(lying)}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+
+	router := chi.NewRouter()
+	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+		DirectInvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/hello")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", string(body))
+	assert.Equal(t, int64(2), resp.ContentLength)
+}
+
+func TestInvokeHandlerBilledDurationHeader(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Empty(t, w.Header().Get(billedDurationHeader))
+
+	t.Setenv(billedDurationHeaderEnvKey, "true")
+	w = invoke(t, sandbox, newInvokeRequest("{}"))
+	billed, err := strconv.Atoi(w.Header().Get(billedDurationHeader))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, billed, 1)
+}
+
+func TestInitHandlerWarnsAboutShadowedReservedEnv(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Setenv("LAMBDA_TASK_ROOT", "/app")
+	t.Setenv("AWS_LAMBDA_LOG_GROUP_NAME", "/aws/lambda/Functions")
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "orders")
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, "/app", sandbox.lastInit.CustomerEnvironmentVariables["LAMBDA_TASK_ROOT"])
+	assert.Contains(t, logs.String(), "LAMBDA_TASK_ROOT from the environment shadow")
+	assert.NotContains(t, logs.String(), "AWS_LAMBDA_LOG_GROUP_NAME", "the same value does not shadow anything")
+	assert.NotContains(t, logs.String(), "AWS_LAMBDA_FUNCTION_NAME", "the function name configures the emulator")
+
+	t.Setenv(strictReservedEnvKey, "true")
+	logs.Reset()
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, "/var/task", sandbox.lastInit.CustomerEnvironmentVariables["LAMBDA_TASK_ROOT"])
+	assert.Contains(t, logs.String(), "Not forwarding LAMBDA_TASK_ROOT")
+}
+
+func TestInvokeHandlerReinitializesWithinTheInvokeAfterFailure(t *testing.T) {
+	t.Cleanup(func() { initFailed = false })
+	failInit := true
+	sandbox := &mockSandbox{}
+	sandbox.invoke = func(w http.ResponseWriter, i *interop.Invoke) error {
+		if failInit {
+			failInit = false
+			return rapidcore.ErrInitDoneFailed
+		}
+		w.Write([]byte(`"ok"`))
+		return nil
+	}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+	assert.False(t, initialized(), "not ready after a failed init")
+
+	w = httptest.NewRecorder()
+	InvokeHandler(w, newInvokeRequest("{}"), sandbox, NewSimpleBootstrap([]string{}, ""))
+	assert.Equal(t, `"ok"`, w.Body.String())
+	assert.Equal(t, 1, sandbox.initCalls, "rapid initializes the function again within the invoke, Init is not repeated")
+	assert.True(t, initialized(), "ready once an invoke succeeded")
+}
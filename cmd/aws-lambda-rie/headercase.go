@@ -0,0 +1,165 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	headerCaseEnvKey = "AWS_LAMBDA_RIE_HEADER_CASE"
+
+	headerCaseCanonical = "canonical"
+	headerCasePreserve  = "preserve"
+	headerCaseLower     = "lower"
+
+	// header blocks larger than this are not recorded, their names stay canonical
+	maxRecordedHeaderBytes = 64 * 1024
+)
+
+var headerBlockEnd = []byte("\r\n\r\n")
+
+type contextKey int
+
+const (
+	recordingConnKey contextKey = iota
+	rawHeaderNamesKey
+	invokeTagsKey
+	logCaptureKey
+)
+
+// headerCaseMode returns the casing configured with AWS_LAMBDA_RIE_HEADER_CASE,
+// or the one of the event format when it is not set
+func headerCaseMode(formatDefault string) string {
+	mode := GetenvWithDefault(headerCaseEnvKey, formatDefault)
+	switch mode {
+	case headerCaseCanonical, headerCasePreserve, headerCaseLower:
+		return mode
+	}
+	log.Warnf("Invalid %s %q, using %s", headerCaseEnvKey, mode, formatDefault)
+	return formatDefault
+}
+
+// eventHeaderName returns the name under which a header of r is put in the event.
+// Each event format passes the casing its trigger delivers headers with.
+func eventHeaderName(r *http.Request, canonical string, formatDefault string) string {
+	switch headerCaseMode(formatDefault) {
+	case headerCaseLower:
+		return strings.ToLower(canonical)
+	case headerCasePreserve:
+		if names, ok := r.Context().Value(rawHeaderNamesKey).(map[string]string); ok {
+			if raw, found := names[canonical]; found {
+				return raw
+			}
+		}
+	}
+	return canonical
+}
+
+// recordingListener wraps accepted connections so that the header names of each
+// request can be read as sent on the wire, net/http only exposes them canonicalized
+type recordingListener struct {
+	net.Listener
+}
+
+func (l recordingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConn{Conn: conn}, nil
+}
+
+// recordingConn records the bytes read from the connection up to the end of the next header block
+type recordingConn struct {
+	net.Conn
+	mutex    sync.Mutex
+	recorded []byte
+	complete bool
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if n > 0 && !c.complete {
+		c.recorded = append(c.recorded, p[:n]...)
+		if end := bytes.Index(c.recorded, headerBlockEnd); end >= 0 {
+			c.recorded = c.recorded[:end]
+			c.complete = true
+		} else if len(c.recorded) > maxRecordedHeaderBytes {
+			c.recorded = nil
+			c.complete = true
+		}
+	}
+	return n, err
+}
+
+// rawHeaderNames maps the canonical names of the headers of r to their names on the wire
+func (c *recordingConn) rawHeaderNames(r *http.Request) map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// anything read before the request line, e.g. the rest of a previous body, is skipped
+	block := string(c.recorded)
+	requestLine := r.Method + " " + r.RequestURI + " "
+	if start := strings.LastIndex(block, requestLine); start >= 0 {
+		block = block[start:]
+	}
+
+	names := map[string]string{}
+	lines := strings.Split(block, "\r\n")
+	for _, line := range lines[1:] {
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 {
+			continue
+		}
+		raw := strings.TrimSpace(line[:colon])
+		canonical := textproto.CanonicalMIMEHeaderKey(raw)
+		if _, found := r.Header[canonical]; found {
+			names[canonical] = raw
+		}
+	}
+	return names
+}
+
+// reset starts recording the header block of the next request on the connection
+func (c *recordingConn) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.recorded = nil
+	c.complete = false
+}
+
+func saveRecordingConn(ctx context.Context, conn net.Conn) context.Context {
+	if rc, ok := conn.(*recordingConn); ok {
+		return context.WithValue(ctx, recordingConnKey, rc)
+	}
+	return ctx
+}
+
+// preserveHeaderCase makes the raw header names of the request available to eventHeaderName. It wraps the server's
+// handler rather than the invoke routes, so that every request on a connection resets the recording for the next one.
+func preserveHeaderCase(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, ok := r.Context().Value(recordingConnKey).(*recordingConn)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer conn.reset()
+		ctx := context.WithValue(r.Context(), rawHeaderNamesKey, conn.rawHeaderNames(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendRaw writes the requests on a single connection, so that header casing is under the test's control
+func sendRaw(t *testing.T, addr string, requests ...string) {
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for _, req := range requests {
+		_, err := conn.Write([]byte(req))
+		assert.NoError(t, err)
+		resp, err := http.ReadResponse(reader, nil)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+}
+
+func TestPreserveHeaderCase(t *testing.T) {
+	var seen []map[string]string
+	server := httptest.NewUnstartedServer(preserveHeaderCase(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := map[string]string{}
+		for k := range r.Header {
+			names[k] = eventHeaderName(r, k, headerCaseCanonical)
+		}
+		seen = append(seen, names)
+	})))
+	server.Listener = recordingListener{server.Listener}
+	server.Config.ConnContext = saveRecordingConn
+	server.Start()
+	defer server.Close()
+
+	t.Setenv(headerCaseEnvKey, headerCasePreserve)
+	sendRaw(t, server.Listener.Addr().String(),
+		"POST /first HTTP/1.1\r\nHost: localhost\r\nx-lower: a\r\nX-MiXeD-Case: b\r\nContent-Length: 4\r\n\r\nbody",
+		"GET /second HTTP/1.1\r\nhost: localhost\r\nCONTENT-TYPE: text/plain\r\n\r\n",
+	)
+
+	assert.Len(t, seen, 2)
+	assert.Equal(t, "x-lower", seen[0]["X-Lower"])
+	assert.Equal(t, "X-MiXeD-Case", seen[0]["X-Mixed-Case"])
+	assert.Equal(t, "CONTENT-TYPE", seen[1]["Content-Type"], "each request on a connection gets its own names")
+	_, leaked := seen[1]["X-Lower"]
+	assert.False(t, leaked)
+}
+
+func TestPreserveHeaderCaseAfterAnAdminRequest(t *testing.T) {
+	var seen map[string]string
+	r := chi.NewRouter()
+	r.Get("/_rie/state", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post(invokePath, func(w http.ResponseWriter, r *http.Request) {
+		seen = map[string]string{"X-Lower": eventHeaderName(r, "X-Lower", headerCaseCanonical)}
+	})
+	server := httptest.NewUnstartedServer(preserveHeaderCase(r))
+	server.Listener = recordingListener{server.Listener}
+	server.Config.ConnContext = saveRecordingConn
+	server.Start()
+	defer server.Close()
+
+	t.Setenv(headerCaseEnvKey, headerCasePreserve)
+	sendRaw(t, server.Listener.Addr().String(),
+		"GET /_rie/state HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"POST "+invokePath+" HTTP/1.1\r\nHost: localhost\r\nx-lower: a\r\nContent-Length: 2\r\n\r\n{}",
+	)
+
+	assert.Equal(t, "x-lower", seen["X-Lower"], "the invoke after another request on the connection keeps its casing")
+}
+
+func TestEventHeaderNameUsesFormatCasing(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.Equal(t, "X-Lower", eventHeaderName(req, "X-Lower", headerCaseCanonical))
+	assert.Equal(t, "x-lower", eventHeaderName(req, "X-Lower", headerCaseLower))
+
+	t.Setenv(headerCaseEnvKey, headerCaseCanonical)
+	assert.Equal(t, "X-Lower", eventHeaderName(req, "X-Lower", headerCaseLower), "the environment overrides the format")
+}
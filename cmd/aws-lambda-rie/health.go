@@ -0,0 +1,116 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	healthPath  = "/health"
+	readyPath   = "/ready"
+	healthzPath = "/healthz"
+
+	deepCheckParam        = "deep"
+	canaryPayloadEnvKey   = "AWS_LAMBDA_RIE_CANARY_PAYLOAD"
+	canaryTimeoutMsEnvKey = "AWS_LAMBDA_RIE_CANARY_TIMEOUT_MS"
+	defaultCanaryTimeout  = 3 * time.Second
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	// the response of a failed canary invoke
+	Canary string `json:"canary,omitempty"`
+}
+
+// HealthHandler answers liveness probes, the emulator is healthy as soon as it accepts HTTP requests
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// ReadyHandler answers readiness probes with a 200 once the function was initialized and a 503 before,
+// or again after a failed init until the next invoke initializes it. With ?deep=true it invokes the function
+// with a canary payload instead, initializing it if needed, and is ready only if the invoke succeeds.
+// The probes are not authenticated, so the deep check is only made when AWS_LAMBDA_RIE_CANARY_PAYLOAD is set.
+func ReadyHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs interop.Bootstrap) {
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get(deepCheckParam)); deep {
+		if payload := GetenvWithDefault(canaryPayloadEnvKey, ""); payload != "" {
+			writeCanaryReadiness(w, r, payload, sandbox, bs)
+			return
+		}
+		log.Debugf("Checking the init only, %s is not set for deep readiness checks", canaryPayloadEnvKey)
+	}
+
+	if !initialized() {
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "initializing"})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// initialized reports a completed init without waiting for an init in progress, which holds initMutex for as long as
+// it runs and is not done yet anyway
+func initialized() bool {
+	if !initMutex.TryLock() {
+		return false
+	}
+	defer initMutex.Unlock()
+	return initDone && !initFailed
+}
+
+type uncountedInvokeKey struct{}
+
+// isUncountedInvoke tells whether InvokeHandler serves the canary, which prints no START and REPORT lines
+// and does not write the response sink
+func isUncountedInvoke(ctx context.Context) bool {
+	uncounted, _ := ctx.Value(uncountedInvokeKey{}).(bool)
+	return uncounted
+}
+
+// writeCanaryReadiness invokes the function directly rather than through the router, so that the canary is
+// neither recorded in the history nor counted in the state of the sandbox
+func writeCanaryReadiness(w http.ResponseWriter, r *http.Request, payload string, sandbox Sandbox, bs interop.Bootstrap) {
+	ctx := context.WithValue(r.Context(), uncountedInvokeKey{}, true)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, invokePath, bytes.NewReader([]byte(payload)))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, healthResponse{Status: "failed", Canary: err.Error()})
+		return
+	}
+	req.Header.Set(timeoutHeader, strconv.FormatFloat(canaryTimeout().Seconds(), 'f', -1, 64))
+
+	resp := newBufferedResponse()
+	InvokeHandler(resp, req, sandbox, bs)
+	switch {
+	case resp.header.Get("Retry-After") != "":
+		// the sandbox is serving another invoke, which the canary would only delay, so nothing was checked
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "busy"})
+	case resp.statusCode != http.StatusOK || resp.header.Get(functionErrorHeader) != "":
+		log.Warnf("Canary invoke failed with status %d: %s", resp.statusCode, redactPayload(resp.body.Bytes()))
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "failed", Canary: resp.body.String()})
+	default:
+		writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+	}
+}
+
+// canaryTimeout bounds the canary invoke, AWS_LAMBDA_RIE_CANARY_TIMEOUT_MS
+func canaryTimeout() time.Duration {
+	configured := GetenvWithDefault(canaryTimeoutMsEnvKey, "")
+	if configured == "" {
+		return defaultCanaryTimeout
+	}
+
+	timeoutMs, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || timeoutMs <= 0 {
+		log.Warnf("Invalid %s %q, using %s", canaryTimeoutMsEnvKey, configured, defaultCanaryTimeout)
+		return defaultCanaryTimeout
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/metering"
+	"go.amzn.com/lambda/rapidcore"
+)
+
+func probe(t *testing.T, handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	ready := func(w http.ResponseWriter, r *http.Request) {
+		ReadyHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}
+
+	health := probe(t, HealthHandler, healthPath)
+	assert.Equal(t, http.StatusOK, health.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, health.Body.String())
+	readiness := probe(t, ready, readyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, readiness.Code, "not initialized before the first invoke")
+	assert.JSONEq(t, `{"status":"initializing"}`, readiness.Body.String())
+
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusOK, probe(t, HealthHandler, healthPath).Code)
+	readiness = probe(t, ready, readyPath)
+	assert.Equal(t, http.StatusOK, readiness.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, readiness.Body.String())
+
+	initMutex.Lock()
+	assert.Equal(t, http.StatusServiceUnavailable, probe(t, ready, readyPath).Code, "an init in progress is not waited for")
+	initMutex.Unlock()
+}
+
+func TestDeepReadinessInvokesACanary(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	t.Setenv(canaryPayloadEnvKey, `{"canary": true}`)
+
+	var payload string
+	var deadline time.Duration
+	sandbox := &mockSandbox{}
+	ready := func(w http.ResponseWriter, r *http.Request) {
+		ReadyHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}
+
+	sandbox.invoke = func(w http.ResponseWriter, i *interop.Invoke) error {
+		body, _ := io.ReadAll(i.Payload)
+		payload = string(body)
+		deadlineNs, _ := strconv.ParseInt(i.DeadlineNs, 10, 64)
+		deadline = time.Duration(deadlineNs - metering.Monotime())
+		w.Write([]byte(`"ok"`))
+		return nil
+	}
+	w := probe(t, ready, healthzPath+"?deep=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	assert.Equal(t, `{"canary": true}`, payload)
+	assert.Equal(t, 1, sandbox.initCalls, "the canary initializes the function")
+	assert.InDelta(t, defaultCanaryTimeout.Seconds(), deadline.Seconds(), 0.5)
+
+	sandbox.invoke = respondWithFunctionError(`{"errorMessage": "boom"}`)
+	w = probe(t, ready, healthzPath+"?deep=true")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "the handler initializes but fails on invoke")
+	assert.Contains(t, w.Body.String(), "boom")
+	assert.Equal(t, http.StatusOK, probe(t, ready, healthzPath).Code, "the shallow check only looks at the init")
+
+	sandbox.invoke = func(w http.ResponseWriter, i *interop.Invoke) error { return rapidcore.ErrAlreadyReserved }
+	w = probe(t, ready, readyPath+"?deep=true")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "a busy sandbox is not checked")
+	assert.JSONEq(t, `{"status":"busy"}`, w.Body.String())
+}
+
+func TestDeepReadinessIsOptIn(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	ready := func(w http.ResponseWriter, r *http.Request) {
+		ReadyHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}
+
+	w := probe(t, ready, readyPath+"?deep=true")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"initializing"}`, w.Body.String(), "the init-state check without a canary payload")
+	assert.Zero(t, sandbox.initCalls, "the function is not run")
+}
+
+func TestCanaryIsNotReported(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	t.Setenv(canaryPayloadEnvKey, "{}")
+	sinkPath := filepath.Join(t.TempDir(), "response")
+	t.Setenv(responseSinkEnvKey, sinkPath)
+	var platformLines bytes.Buffer
+	platformLog = &platformLines
+	t.Cleanup(func() { platformLog = os.Stdout })
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+
+	w := probe(t, func(w http.ResponseWriter, r *http.Request) {
+		ReadyHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}, readyPath+"?deep=true")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, platformLines.String(), "START RequestId")
+	assert.NotContains(t, platformLines.String(), "REPORT RequestId")
+	assert.NoFileExists(t, sinkPath)
+}
@@ -0,0 +1,174 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	historySizeEnvKey  = "AWS_LAMBDA_RIE_HISTORY_SIZE"
+	defaultHistorySize = 20
+
+	requestIDHeader = "X-Amzn-RequestId"
+)
+
+// capturedRequest is the client request as received by the emulator,
+// before any event mapping, so that it can be replayed as-is
+type capturedRequest struct {
+	Method string
+	URI    string
+	Host   string
+	Header http.Header
+	Body   []byte
+}
+
+type invocationRecord struct {
+	RequestID  string    `json:"requestId"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"durationMs"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	request capturedRequest
+}
+
+// invocationHistory keeps the last N invocations in memory
+type invocationHistory struct {
+	mutex   sync.Mutex
+	size    int
+	records []invocationRecord
+}
+
+func newInvocationHistory(size int) *invocationHistory {
+	return &invocationHistory{size: size}
+}
+
+func newInvocationHistoryFromEnv() *invocationHistory {
+	size, err := strconv.Atoi(GetenvWithDefault(historySizeEnvKey, strconv.Itoa(defaultHistorySize)))
+	if err != nil || size < 0 {
+		log.Warnf("Invalid %s, using default of %d", historySizeEnvKey, defaultHistorySize)
+		size = defaultHistorySize
+	}
+	return newInvocationHistory(size)
+}
+
+func (h *invocationHistory) add(record invocationRecord) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.size == 0 {
+		return
+	}
+
+	h.records = append(h.records, record)
+	if len(h.records) > h.size {
+		h.records = h.records[len(h.records)-h.size:]
+	}
+}
+
+// list returns the recorded invocations, most recent first
+func (h *invocationHistory) list() []invocationRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	records := make([]invocationRecord, 0, len(h.records))
+	for i := len(h.records) - 1; i >= 0; i-- {
+		records = append(records, h.records[i])
+	}
+	return records
+}
+
+func (h *invocationHistory) get(requestID string) (invocationRecord, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, record := range h.records {
+		if record.RequestID == requestID {
+			return record, true
+		}
+	}
+	return invocationRecord{}, false
+}
+
+// recordInvocation captures the incoming request and the outcome of the invoke
+// in the history. The request ID is taken from the response header set by InvokeHandler.
+// The body is captured as the handler reads it, so that it is bounded by the handler's payload limit.
+func recordInvocation(history *invocationHistory) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body bytes.Buffer
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, &body), r.Body}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			requestID := ww.Header().Get(requestIDHeader)
+			if requestID == "" {
+				// the request never reached the sandbox
+				return
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			history.add(invocationRecord{
+				RequestID:  requestID,
+				Path:       r.URL.Path,
+				Status:     status,
+				DurationMs: float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+				Timestamp:  start.UTC(),
+				request: capturedRequest{
+					Method: r.Method,
+					URI:    r.URL.RequestURI(),
+					Host:   r.Host,
+					Header: r.Header.Clone(),
+					Body:   body.Bytes(),
+				},
+			})
+		})
+	}
+}
+
+func HistoryHandler(w http.ResponseWriter, r *http.Request, history *invocationHistory) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"invocations": history.list()})
+}
+
+// ReplayHandler re-runs a captured invocation through the router, so that the
+// replayed request goes through the same route (and event mapping) as the original
+func ReplayHandler(w http.ResponseWriter, r *http.Request, history *invocationHistory, router http.Handler) {
+	requestID := chi.URLParam(r, "id")
+	record, found := history.get(requestID)
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "ResourceNotFound", "No invocation with request ID "+requestID+" in history")
+		return
+	}
+
+	replay, err := http.NewRequest(record.request.Method, record.request.URI, bytes.NewReader(record.request.Body))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "ReplayFailed", err.Error())
+		return
+	}
+	replay.Header = record.request.Header.Clone()
+	replay.Host = record.request.Host
+	replay.RemoteAddr = r.RemoteAddr
+
+	log.Infof("Replaying invocation %s", requestID)
+	router.ServeHTTP(w, replay)
+}
@@ -0,0 +1,105 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyRouter records the invokes of a handler that echoes the body read within limit, with a request ID per invoke
+func historyRouter(history *invocationHistory, limit int64) *chi.Mux {
+	invokes := 0
+	r := chi.NewRouter()
+	r.Post("/_rie/history/{id}/replay", func(w http.ResponseWriter, req *http.Request) { ReplayHandler(w, req, history, r) })
+	r.With(recordInvocation(history)).Post(invokePath, func(w http.ResponseWriter, req *http.Request) {
+		invokes++
+		w.Header().Set(requestIDHeader, "request-"+strconv.Itoa(invokes))
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, limit))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Header().Set("X-Echo-Header", req.Header.Get("X-Test"))
+		w.Write(body)
+	})
+	return r
+}
+
+func TestRecordInvocation(t *testing.T) {
+	history := newInvocationHistory(2)
+	router := historyRouter(history, 1024)
+
+	for _, body := range []string{`{"n": 1}`, `{"n": 2}`, `{"n": 3}`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newInvokeRequest(body))
+		assert.Equal(t, body, w.Body.String(), "the handler reads the body")
+	}
+
+	records := history.list()
+	require.Len(t, records, 2, "only the last invocations are kept")
+	assert.Equal(t, "request-3", records[0].RequestID, "most recent first")
+	assert.Equal(t, "request-2", records[1].RequestID)
+	assert.Equal(t, `{"n": 3}`, string(records[0].request.Body))
+	assert.Equal(t, invokePath, records[0].Path)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+
+	w := httptest.NewRecorder()
+	HistoryHandler(w, httptest.NewRequest(http.MethodGet, "/_rie/history", nil), history)
+	var listed struct {
+		Invocations []invocationRecord `json:"invocations"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed.Invocations, 2)
+}
+
+func TestRecordInvocationBoundedByTheHandlerLimit(t *testing.T) {
+	history := newInvocationHistory(1)
+	router := historyRouter(history, 8)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newInvokeRequest(strings.Repeat("a", 1<<20)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	records := history.list()
+	require.Len(t, records, 1)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, records[0].Status)
+	assert.Less(t, len(records[0].request.Body), 1024, "the body is not read past the limit")
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	history := newInvocationHistory(0)
+	historyRouter(history, 1024).ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	assert.Empty(t, history.list())
+}
+
+func TestReplayHandler(t *testing.T) {
+	history := newInvocationHistory(5)
+	router := historyRouter(history, 1024)
+	req := newInvokeRequest(`{"replay": true}`)
+	req.Header.Set("X-Test", "kept")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/_rie/history/request-1/replay", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"replay": true}`, w.Body.String())
+	assert.Equal(t, "kept", w.Header().Get("X-Echo-Header"), "the original headers are replayed")
+	assert.Equal(t, "request-2", w.Header().Get(requestIDHeader))
+	assert.Len(t, history.list(), 2, "the replay is recorded as an invocation of its own")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/_rie/history/unknown/replay", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "ResourceNotFound")
+}
@@ -4,6 +4,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"net"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -12,15 +14,75 @@ import (
 	"go.amzn.com/lambda/rapidcore"
 )
 
-func startHTTPServer(ipport string, sandbox *rapidcore.SandboxBuilder, bs interop.Bootstrap) {
+const invokePath = "/2015-03-31/functions/function/invocations"
+
+func startHTTPServer(ipport string, sandbox *rapidcore.SandboxBuilder, bs interop.Bootstrap, logs *invocationLogs, chaos *runtimeAPIChaos, shutdown *gracefulShutdown, functions *functionRouter, tlsConfig *tls.Config) {
+	history := newInvocationHistoryFromEnv()
+	lambdaInvokeAPI := newTrackedSandbox("0", sandbox.LambdaInvokeAPI())
+	coldStarts := newColdStartsFromEnv(sandbox.DefaultInteropServer().Reset, lambdaInvokeAPI)
+	gate := newInvokeGate()
+	idempotent := newIdempotentInvokesFromEnv()
 
 	r := chi.NewRouter()
-	r.Post("/2015-03-31/functions/function/invocations", func(w http.ResponseWriter, r *http.Request) { InvokeHandler(w, r, sandbox.LambdaInvokeAPI(), bs) })
-	r.Post("/*", func(w http.ResponseWriter, r *http.Request) { DirectInvokeHandler(w, r, sandbox.LambdaInvokeAPI(), bs) })
+	r.Use(bufferHTTP10)
+	r.Get(healthPath, HealthHandler)
+	ready := func(w http.ResponseWriter, req *http.Request) { ReadyHandler(w, req, sandbox.LambdaInvokeAPI(), bs) }
+	r.Get(readyPath, ready)
+	r.Get(healthzPath, ready)
+	r.Route(adminPathPrefix, func(rie chi.Router) {
+		rie.NotFound(adminNotFound(rie))
+		rie.Get("/ui", UIHandler)
+		rie.Group(func(admin chi.Router) {
+			admin.Use(adminOnly)
+			admin.Get("/history", func(w http.ResponseWriter, req *http.Request) { HistoryHandler(w, req, history) })
+			admin.Post("/history/{id}/replay", func(w http.ResponseWriter, req *http.Request) { ReplayHandler(w, req, history, r) })
+			admin.Get("/state", func(w http.ResponseWriter, req *http.Request) {
+				StateHandler(w, req, []*trackedSandbox{lambdaInvokeAPI}, sandbox.DefaultInteropServer().InternalState)
+			})
+			admin.Post("/sample/{name}", func(w http.ResponseWriter, req *http.Request) { SampleHandler(w, req, r) })
+			admin.Get("/invocations/{id}/logs", func(w http.ResponseWriter, req *http.Request) { InvocationLogsHandler(w, req, logs) })
+			admin.Post("/chaos/runtime-api", func(w http.ResponseWriter, req *http.Request) { RuntimeAPIChaosHandler(w, req, chaos) })
+			admin.Delete("/chaos/runtime-api", func(w http.ResponseWriter, req *http.Request) { RuntimeAPIChaosHandler(w, req, chaos) })
+			admin.Post("/pause", func(w http.ResponseWriter, req *http.Request) { PauseHandler(w, req, gate) })
+			admin.Post("/resume", func(w http.ResponseWriter, req *http.Request) { ResumeHandler(w, req, gate) })
+			admin.Post("/checkpoint", func(w http.ResponseWriter, req *http.Request) {
+				CheckpointHandler(w, req, lambdaInvokeAPI, sandbox.DefaultInteropServer(), bs)
+			})
+			admin.Post("/restore", func(w http.ResponseWriter, req *http.Request) { RestoreHandler(w, req, sandbox.DefaultInteropServer()) })
+			admin.Post("/reset", func(w http.ResponseWriter, req *http.Request) {
+				ResetHandler(w, req, sandbox.DefaultInteropServer().Reset)
+			})
+			admin.Post("/warmup", func(w http.ResponseWriter, req *http.Request) { WarmupHandler(w, req, sandbox.LambdaInvokeAPI(), bs) })
+		})
+	})
 
-	if err := http.ListenAndServe(ipport, r); err != nil {
-		log.Panic(err)
+	invokeMiddlewares := chi.Middlewares{answerRootInfo, answerPings, idempotent.middleware, gate.middleware, recordInvocation(history), captureLogs(logs), extractInvokeTags, coldStarts.middleware}
+	invoke := func(w http.ResponseWriter, r *http.Request) { InvokeHandler(w, r, lambdaInvokeAPI, bs) }
+	invocations := r.With(invokeMiddlewares...)
+	invocations.Post(invokePath, invoke)
+	if functions != nil {
+		// the other functions' emulators apply the middlewares to their own invokes
+		r.Post(functionInvokePath, functions.handler(invokeMiddlewares.HandlerFunc(invoke)))
 	}
+	invocations.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) { DirectInvokeHandler(w, r, lambdaInvokeAPI, bs) })
 
-	log.Warnf("Listening on %s", ipport)
+	listener, err := net.Listen("tcp", ipport)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to listen on %s, the port may be in use by another process", ipport)
+	}
+	if tlsConfig != nil {
+		// the records of header names are taken from the decrypted stream
+		listener = tls.NewListener(listener, tlsConfig)
+		log.Warnf("Listening on %s with TLS", ipport)
+	} else {
+		log.Warnf("Listening on %s", ipport)
+	}
+
+	server := &http.Server{Handler: preserveHeaderCase(r), ConnContext: saveRecordingConn}
+	shutdown.serve(server)
+	if err := server.Serve(recordingListener{listener}); err != http.ErrServerClosed {
+		log.Panic(err)
+	}
+	// the shutdown functions run after the drain exit the process
+	select {}
 }
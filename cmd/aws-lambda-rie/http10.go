@@ -0,0 +1,29 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// bufferHTTP10 sends complete responses with a Content-Length to HTTP/1.0 clients, which do not
+// understand chunked bodies. Handlers get a writer that is not a Flusher, so streamed function
+// responses fall back to buffering. net/http keeps 1.0 connections alive only when the client
+// sent Connection: keep-alive, which a known Content-Length makes possible.
+func bufferHTTP10(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoAtLeast(1, 1) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resp := newBufferedResponse()
+		next.ServeHTTP(resp, r)
+		if resp.statusCode >= http.StatusOK && resp.statusCode != http.StatusNoContent && resp.statusCode != http.StatusNotModified {
+			resp.header.Set("Content-Length", strconv.Itoa(resp.body.Len()))
+		}
+		resp.copyTo(w)
+	})
+}
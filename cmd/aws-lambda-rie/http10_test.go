@@ -0,0 +1,73 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendHTTP10 sends a raw HTTP/1.0 request, net/http clients only speak HTTP/1.1 and later
+func sendHTTP10(t *testing.T, server *httptest.Server, headers string, body string) *http.Response {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "POST %s HTTP/1.0\r\nContent-Length: %d\r\n%s\r\n%s", invokePath, len(body), headers, body)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestHTTP10Invoke(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	large := `"` + strings.Repeat("a", 8192) + `"`
+	sandbox := &mockSandbox{invoke: respondWith(large)}
+	server := httptest.NewServer(bufferHTTP10(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	})))
+	defer server.Close()
+
+	resp := sendHTTP10(t, server, "", "{}")
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.TransferEncoding)
+	assert.Equal(t, strconv.Itoa(len(large)), resp.Header.Get("Content-Length"))
+	assert.Equal(t, large, string(body))
+	assert.True(t, resp.Close, "1.0 connections are closed unless keep-alive is requested")
+}
+
+func TestHTTP10BuffersStreamedResponses(t *testing.T) {
+	server := httptest.NewServer(bufferHTTP10(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, canFlush := w.(http.Flusher)
+		assert.False(t, canFlush)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte(strings.Repeat("x", 2048)))
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+	})))
+	defer server.Close()
+
+	resp := sendHTTP10(t, server, "Connection: keep-alive\r\n", "")
+
+	assert.Empty(t, resp.TransferEncoding)
+	assert.Equal(t, "6144", resp.Header.Get("Content-Length"))
+	assert.Equal(t, "keep-alive", resp.Header.Get("Connection"))
+	assert.False(t, resp.Close)
+}
@@ -0,0 +1,181 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyEnvKey           = "AWS_LAMBDA_RIE_IDEMPOTENCY"
+	idempotencyTTLEnvKey        = "AWS_LAMBDA_RIE_IDEMPOTENCY_TTL_MS"
+	idempotencyCacheSizeEnvKey  = "AWS_LAMBDA_RIE_IDEMPOTENCY_CACHE_SIZE"
+	defaultIdempotencyTTL       = 5 * time.Minute
+	defaultIdempotencyCacheSize = 100
+
+	idempotencyKeyHeader   = "Idempotency-Key"
+	idempotentReplayHeader = "X-Rie-Idempotent-Replay"
+)
+
+// idempotentResponse is the response of the first request with a key, done is closed once it is complete
+type idempotentResponse struct {
+	done    chan struct{}
+	expires time.Time
+	// false when the response was not kept, e.g. a throttle, and the retries run the function
+	kept   bool
+	status int
+	header http.Header
+	body   []byte
+}
+
+// idempotentInvokes answers the retries of a request with the response of its first attempt instead of invoking
+// the function again, for requests that carry an Idempotency-Key or X-Amzn-RequestId header
+type idempotentInvokes struct {
+	ttl   time.Duration
+	size  int
+	mutex sync.Mutex
+	// keys in the order they were first seen, the oldest is evicted first
+	keys      []string
+	responses map[string]*idempotentResponse
+}
+
+// newIdempotentInvokesFromEnv returns nil unless AWS_LAMBDA_RIE_IDEMPOTENCY is true
+func newIdempotentInvokesFromEnv() *idempotentInvokes {
+	if GetenvWithDefault(idempotencyEnvKey, "false") != "true" {
+		return nil
+	}
+
+	ttl := defaultIdempotencyTTL
+	if configured := GetenvWithDefault(idempotencyTTLEnvKey, ""); configured != "" {
+		ttlMs, err := strconv.ParseInt(configured, 10, 64)
+		if err != nil || ttlMs <= 0 {
+			log.Warnf("Invalid %s %q, using %s", idempotencyTTLEnvKey, configured, defaultIdempotencyTTL)
+		} else {
+			ttl = time.Duration(ttlMs) * time.Millisecond
+		}
+	}
+
+	size, err := strconv.Atoi(GetenvWithDefault(idempotencyCacheSizeEnvKey, strconv.Itoa(defaultIdempotencyCacheSize)))
+	if err != nil || size <= 0 {
+		log.Warnf("Invalid %s, using default of %d", idempotencyCacheSizeEnvKey, defaultIdempotencyCacheSize)
+		size = defaultIdempotencyCacheSize
+	}
+	log.Infof("Answering retries with the first response for %s, keeping up to %d responses", ttl, size)
+
+	return newIdempotentInvokes(ttl, size)
+}
+
+func newIdempotentInvokes(ttl time.Duration, size int) *idempotentInvokes {
+	return &idempotentInvokes{ttl: ttl, size: size, responses: map[string]*idempotentResponse{}}
+}
+
+// claim returns the response of an earlier request with the key, or registers the request as the first one
+func (c *idempotentInvokes) claim(key string) (*idempotentResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if response, found := c.responses[key]; found {
+		select {
+		case <-response.done:
+			if response.kept && time.Now().Before(response.expires) {
+				return response, false
+			}
+		default:
+			// the first request is still running
+			return response, false
+		}
+		c.remove(key)
+	}
+
+	response := &idempotentResponse{done: make(chan struct{})}
+	c.responses[key] = response
+	c.keys = append(c.keys, key)
+	for len(c.keys) > c.size {
+		c.remove(c.keys[0])
+	}
+	return response, true
+}
+
+func (c *idempotentInvokes) remove(key string) {
+	delete(c.responses, key)
+	for i, k := range c.keys {
+		if k == key {
+			c.keys = append(c.keys[:i], c.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *idempotentInvokes) complete(key string, response *idempotentResponse, status int, header http.Header, body []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// throttles and failures of the emulator are retried, like the requests that never reached the function
+	response.kept = status != http.StatusTooManyRequests && status < http.StatusInternalServerError
+	response.status = status
+	response.header = header
+	response.body = body
+	response.expires = time.Now().Add(c.ttl)
+	close(response.done)
+
+	if !response.kept && c.responses[key] == response {
+		c.remove(key)
+	}
+}
+
+// middleware is installed on the invoke routes, before those that record an invoke
+func (c *idempotentInvokes) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			key = r.Header.Get(requestIDHeader)
+		}
+		if c == nil || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = r.URL.Path + " " + key
+
+		for {
+			response, first := c.claim(key)
+			if first {
+				c.serve(w, r, next, key, response)
+				return
+			}
+
+			<-response.done
+			if response.kept {
+				log.Infof("Answering retry of %s with the response of the first request", key)
+				for k, vs := range response.header {
+					w.Header()[k] = vs
+				}
+				w.Header().Set(idempotentReplayHeader, "true")
+				w.WriteHeader(response.status)
+				w.Write(response.body)
+				return
+			}
+		}
+	})
+}
+
+func (c *idempotentInvokes) serve(w http.ResponseWriter, r *http.Request, next http.Handler, key string, response *idempotentResponse) {
+	var body bytes.Buffer
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	ww.Tee(&body)
+	defer func() {
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		c.complete(key, response, status, ww.Header().Clone(), body.Bytes())
+	}()
+	next.ServeHTTP(ww, r)
+}
@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentInvokes(t *testing.T) {
+	var mutex sync.Mutex
+	invokes := 0
+	status := http.StatusOK
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		invokes++
+		count := invokes
+		mutex.Unlock()
+		w.Header().Set("X-Invoke", strconv.Itoa(count))
+		w.WriteHeader(status)
+		w.Write([]byte(`"invoke ` + strconv.Itoa(count) + `"`))
+	})
+	serve := func(c *idempotentInvokes, header string, key string) *httptest.ResponseRecorder {
+		req := newInvokeRequest("{}")
+		if header != "" {
+			req.Header.Set(header, key)
+		}
+		w := httptest.NewRecorder()
+		c.middleware(next).ServeHTTP(w, req)
+		return w
+	}
+
+	serve(nil, idempotencyKeyHeader, "a")
+	serve(nil, idempotencyKeyHeader, "a")
+	assert.Equal(t, 2, invokes, "retries are invoked unless enabled")
+
+	invokes = 0
+	c := newIdempotentInvokes(time.Minute, 2)
+	first := serve(c, idempotencyKeyHeader, "a")
+	retry := serve(c, idempotencyKeyHeader, "a")
+	assert.Equal(t, 1, invokes)
+	assert.Equal(t, `"invoke 1"`, retry.Body.String())
+	assert.Equal(t, "1", retry.Header().Get("X-Invoke"))
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayHeader))
+	assert.Empty(t, first.Header().Get(idempotentReplayHeader))
+
+	serve(c, requestIDHeader, "b")
+	assert.Equal(t, `"invoke 2"`, serve(c, requestIDHeader, "b").Body.String(), "X-Amzn-RequestId is a key too")
+	serve(c, "", "")
+	serve(c, "", "")
+	assert.Equal(t, 4, invokes, "requests without a key are always invoked")
+
+	serve(c, idempotencyKeyHeader, "c")
+	assert.Equal(t, `"invoke 6"`, serve(c, idempotencyKeyHeader, "a").Body.String(), "the oldest key is evicted")
+
+	status = http.StatusTooManyRequests
+	serve(c, idempotencyKeyHeader, "throttled")
+	status = http.StatusOK
+	assert.Equal(t, `"invoke 8"`, serve(c, idempotencyKeyHeader, "throttled").Body.String(), "throttles are retried")
+
+	c = newIdempotentInvokes(time.Millisecond, 10)
+	invokes = 0
+	serve(c, idempotencyKeyHeader, "a")
+	time.Sleep(5 * time.Millisecond)
+	serve(c, idempotencyKeyHeader, "a")
+	assert.Equal(t, 2, invokes, "responses expire")
+}
+
+func TestIdempotentInvokesWaitForTheFirstAttempt(t *testing.T) {
+	invokes := 0
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invokes++
+		close(started)
+		<-release
+		w.Write([]byte(`"done"`))
+	})
+	c := newIdempotentInvokes(time.Minute, 10)
+	serve := func() *httptest.ResponseRecorder {
+		req := newInvokeRequest("{}")
+		req.Header.Set(idempotencyKeyHeader, "a")
+		w := httptest.NewRecorder()
+		c.middleware(next).ServeHTTP(w, req)
+		return w
+	}
+
+	go serve()
+	<-started
+	retried := make(chan *httptest.ResponseRecorder)
+	go func() { retried <- serve() }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, `"done"`, (<-retried).Body.String())
+	assert.Equal(t, 1, invokes)
+}
@@ -0,0 +1,103 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+)
+
+const initErrorEventType = "platform.initError"
+
+// initFailures is the last of the events APIs rapid sends its events through, see platformEvents
+var initFailures = newInitFailureReporter(os.Stderr)
+
+type initErrorRecord struct {
+	Phase        interop.InitPhase `json:"phase"`
+	ErrorType    string            `json:"errorType"`
+	ErrorMessage string            `json:"errorMessage,omitempty"`
+}
+
+type initErrorEvent struct {
+	Time   string          `json:"time"`
+	Type   string          `json:"type"`
+	Record initErrorRecord `json:"record"`
+}
+
+// initFailureReporter learns from rapid's INIT RTDONE event that init failed. The failure
+// is reported once the invoke returns, since only its response carries the error message
+// the runtime sent to /init/error.
+type initFailureReporter struct {
+	telemetry.NoOpEventsAPI
+	mutex   sync.Mutex
+	pending *interop.InitRuntimeDoneData
+	stderr  io.Writer
+}
+
+func newInitFailureReporter(stderr io.Writer) *initFailureReporter {
+	return &initFailureReporter{stderr: stderr}
+}
+
+func (r *initFailureReporter) SendInitRuntimeDone(data interop.InitRuntimeDoneData) error {
+	if data.Status == telemetry.RuntimeDoneSuccess {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pending = &data
+	return nil
+}
+
+var _ interop.EventsAPI = (*initFailureReporter)(nil)
+
+// report emits the platform.initError event and a stderr line for the pending init failure, if any, and
+// tells whether there was one. responseBody is the error the runtime reported, e.g. {"errorMessage": "...", "errorType": "..."}.
+func (r *initFailureReporter) report(responseBody []byte) bool {
+	r.mutex.Lock()
+	failure := r.pending
+	r.pending = nil
+	r.mutex.Unlock()
+
+	if failure == nil {
+		return false
+	}
+
+	record := initErrorRecord{Phase: failure.Phase, ErrorType: "Runtime.Unknown"}
+	if failure.ErrorType != nil {
+		record.ErrorType = *failure.ErrorType
+	}
+	var reported struct {
+		ErrorType    string `json:"errorType"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(responseBody, &reported); err == nil {
+		record.ErrorMessage = reported.ErrorMessage
+		if reported.ErrorType != "" {
+			record.ErrorType = reported.ErrorType
+		}
+	}
+
+	event, err := json.Marshal(initErrorEvent{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		Type:   initErrorEventType,
+		Record: record,
+	})
+	if err != nil {
+		log.Errorf("Failed to marshal %s event: %s", initErrorEventType, err)
+		return true
+	}
+
+	fmt.Fprintln(platformLog, string(event))
+	fmt.Fprintf(r.stderr, "INIT_ERROR ErrorType: %s ErrorMessage: %q\n", record.ErrorType, record.ErrorMessage)
+	return true
+}
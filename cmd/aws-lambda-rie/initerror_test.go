@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitFailureReporter(t *testing.T) {
+	var platform, stderr bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+	reporter := newInitFailureReporter(&stderr)
+
+	reporter.SendInitRuntimeDone(interop.InitRuntimeDoneData{Status: telemetry.RuntimeDoneSuccess, Phase: telemetry.InitInsideInitPhase})
+	reporter.report([]byte(`"ok"`))
+	assert.Empty(t, platform.String(), "successful inits are not reported")
+
+	errorType := "Runtime.ExitError"
+	reporter.SendInitRuntimeDone(interop.InitRuntimeDoneData{Status: "error", Phase: telemetry.InitInsideInitPhase, ErrorType: &errorType})
+	reporter.report([]byte(`{"errorMessage": "Unable to import module 'app'", "errorType": "Runtime.ImportModuleError"}`))
+
+	var event initErrorEvent
+	assert.NoError(t, json.Unmarshal(platform.Bytes(), &event))
+	assert.Equal(t, initErrorEventType, event.Type)
+	assert.Equal(t, initErrorRecord{Phase: telemetry.InitInsideInitPhase, ErrorType: "Runtime.ImportModuleError", ErrorMessage: "Unable to import module 'app'"}, event.Record)
+	assert.True(t, strings.HasPrefix(stderr.String(), "INIT_ERROR ErrorType: Runtime.ImportModuleError"))
+
+	platform.Reset()
+	reporter.report(nil)
+	assert.Empty(t, platform.String(), "a failure is reported once")
+}
@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.amzn.com/lambda/interop"
+)
+
+// initDurations forwards the events rapid sends to initFailures
+var initDurations = &initDurationRecorder{EventsAPI: initFailures}
+
+// initDurationRecorder keeps the duration rapid measured for the last init, from the start of the runtime and
+// extensions to the runtime's first /next, until the REPORT line of the invoke that followed it reports it.
+// Init returns as soon as the sandbox starts, so timing the Init call itself would report next to nothing.
+type initDurationRecorder struct {
+	interop.EventsAPI
+	mutex   sync.Mutex
+	pending *float64
+}
+
+func (r *initDurationRecorder) SendInitReport(data interop.InitReportData) error {
+	r.mutex.Lock()
+	durationMs := data.Metrics.DurationMs
+	r.pending = &durationMs
+	r.mutex.Unlock()
+	return r.EventsAPI.SendInitReport(data)
+}
+
+// take returns the duration of the init that completed since the last call, if any
+func (r *initDurationRecorder) take() (float64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.pending == nil {
+		return 0, false
+	}
+	durationMs := *r.pending
+	r.pending = nil
+	return durationMs, true
+}
+
+// measuredInitDuration replaces the Init Duration of the REPORT line with the one rapid measured, when an init
+// completed during the invoke: the init the invoke started or one rapid ran again after a failure. A cold invoke
+// first waited for its init, which Lambda does not count in the Duration, so its start is moved to the end of init.
+func measuredInitDuration(initDurationMs float64, invokeStart time.Time, coldStart bool) (float64, time.Time) {
+	initTimeMS, measured := initDurations.take()
+	if !measured {
+		return initDurationMs, invokeStart
+	}
+
+	warnSlowInit(initTimeMS)
+	initEnd := invokeStart.Add(time.Duration(initTimeMS * float64(time.Millisecond)))
+	if coldStart && initEnd.Before(time.Now()) {
+		invokeStart = initEnd
+	}
+	return initTimeMS, invokeStart
+}
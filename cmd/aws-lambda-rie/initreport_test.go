@@ -0,0 +1,55 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.amzn.com/lambda/interop"
+)
+
+func TestReportCarriesTheMeasuredInitDuration(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+	initDurations.take()
+
+	var initReported bool
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		time.Sleep(300 * time.Millisecond)
+		if !initReported {
+			// rapid reports init once the runtime asks for the invoke
+			initReported = true
+			initDurations.SendInitReport(interop.InitReportData{Metrics: interop.InitReportMetrics{DurationMs: 250}})
+		}
+		w.Write([]byte(`"ok"`))
+		return nil
+	}}
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	bs := NewSimpleBootstrap([]string{}, "")
+	InvokeHandler(httptest.NewRecorder(), newInvokeRequest("{}"), sandbox, bs)
+	InvokeHandler(httptest.NewRecorder(), newInvokeRequest("{}"), sandbox, bs)
+
+	report := regexp.MustCompile(`REPORT RequestId: \S+\tInit Duration: (\d+\.\d{2}) ms\tDuration: (\d+\.\d{2}) ms`)
+	reports := report.FindAllStringSubmatch(platform.String(), -1)
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, "250.00", reports[0][1], "the cold invoke reports the init rapid measured")
+	coldDuration, _ := strconv.ParseFloat(reports[0][2], 64)
+	assert.Less(t, coldDuration, 250.0, "the cold invoke's Duration leaves its init out")
+
+	assert.Equal(t, "0.00", reports[1][1])
+	warmDuration, _ := strconv.ParseFloat(reports[1][2], 64)
+	assert.GreaterOrEqual(t, warmDuration, 300.0)
+}
@@ -0,0 +1,122 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	invokeModeEnvKey         = "AWS_LAMBDA_RIE_INVOKE_MODE"
+	invokeModeBuffered       = "BUFFERED"
+	invokeModeResponseStream = "RESPONSE_STREAM"
+
+	// streamed responses of this type start with a JSON prelude, followed by 8 NUL bytes and the body
+	// see https://docs.aws.amazon.com/lambda/latest/dg/response-streaming-tutorial.html
+	httpIntegrationContentType = "application/vnd.awslambda.http-integration-response"
+)
+
+var httpIntegrationDelimiter = make([]byte, 8)
+
+// invokeMode is the invoke mode of the Function URL: BUFFERED interprets the response envelope once the
+// function returns, RESPONSE_STREAM sends the response to the client as the function streams it
+func invokeMode() string {
+	mode := GetenvWithDefault(invokeModeEnvKey, invokeModeBuffered)
+	switch mode {
+	case invokeModeBuffered, invokeModeResponseStream:
+		return mode
+	}
+	log.Warnf("Invalid %s %q, using %s", invokeModeEnvKey, mode, invokeModeBuffered)
+	return invokeModeBuffered
+}
+
+type httpIntegrationPrelude struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers"`
+	Cookies    []string          `json:"cookies"`
+}
+
+// streamingResponse forwards the response of InvokeHandler to the client as it is produced, like a Function
+// URL in RESPONSE_STREAM mode. The status code and headers of an HTTP integration response are taken from its
+// prelude, any other response is passed through unchanged.
+type streamingResponse struct {
+	w          http.ResponseWriter
+	statusCode int
+	prelude    bytes.Buffer
+	started    bool
+}
+
+func newStreamingResponse(w http.ResponseWriter) *streamingResponse {
+	return &streamingResponse{w: w, statusCode: http.StatusOK}
+}
+
+func (s *streamingResponse) Header() http.Header {
+	return s.w.Header()
+}
+
+// WriteHeader is deferred until the prelude, if any, has been read
+func (s *streamingResponse) WriteHeader(statusCode int) {
+	s.statusCode = statusCode
+}
+
+func (s *streamingResponse) Write(p []byte) (int, error) {
+	if s.started {
+		return s.w.Write(p)
+	}
+	if s.Header().Get("Content-Type") != httpIntegrationContentType {
+		s.start()
+		return s.w.Write(p)
+	}
+
+	s.prelude.Write(p)
+	buffered := s.prelude.Bytes()
+	end := bytes.Index(buffered, httpIntegrationDelimiter)
+	if end < 0 {
+		return len(p), nil
+	}
+
+	var prelude httpIntegrationPrelude
+	if err := json.Unmarshal(buffered[:end], &prelude); err != nil {
+		log.Errorf("Invalid function response: the prelude of the streamed response is not JSON: %s", err)
+	}
+	s.Header().Del("Content-Type")
+	for k, v := range prelude.Headers {
+		s.Header().Set(k, v)
+	}
+	for _, cookie := range prelude.Cookies {
+		s.Header().Add("Set-Cookie", cookie)
+	}
+	if prelude.StatusCode != 0 {
+		s.statusCode = prelude.StatusCode
+	}
+	s.start()
+	if _, err := s.w.Write(buffered[end+len(httpIntegrationDelimiter):]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *streamingResponse) Flush() {
+	if flusher, ok := s.w.(http.Flusher); ok && s.started {
+		flusher.Flush()
+	}
+}
+
+func (s *streamingResponse) start() {
+	s.started = true
+	s.w.WriteHeader(s.statusCode)
+}
+
+// finish sends what was not sent yet, e.g. a response without body or a prelude that never ended
+func (s *streamingResponse) finish() {
+	if s.started {
+		return
+	}
+	s.start()
+	s.w.Write(s.prelude.Bytes())
+}
@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/core/directinvoke"
+	"go.amzn.com/lambda/interop"
+)
+
+// streamHTTPIntegrationResponse streams a response the way the runtimes' streamifyResponse helpers do
+func streamHTTPIntegrationResponse(chunks ...string) func(w http.ResponseWriter, i *interop.Invoke) error {
+	return func(w http.ResponseWriter, i *interop.Invoke) error {
+		w.Header().Set(directinvoke.ContentTypeHeader, httpIntegrationContentType)
+		w.Header().Set(directinvoke.FunctionResponseModeHeader, "streaming")
+		for _, chunk := range chunks {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		return nil
+	}
+}
+
+func TestDirectInvokeResponseStreamMode(t *testing.T) {
+	t.Setenv(invokeModeEnvKey, invokeModeResponseStream)
+	prelude := `{"statusCode": 201, "headers": {"Content-Type": "text/plain"}, "cookies": ["a=1", "b=2"]}`
+	sandbox := &mockSandbox{invoke: streamHTTPIntegrationResponse(prelude[:10], prelude[10:]+"\x00\x00\x00\x00", "\x00\x00\x00\x00first ", "second")}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"a=1", "b=2"}, w.Header().Values("Set-Cookie"))
+	assert.NotEmpty(t, w.Header().Get(requestIDHeader))
+	assert.Equal(t, "first second", w.Body.String())
+	assert.True(t, w.Flushed)
+}
+
+func TestDirectInvokeResponseStreamModeDoesNotUnwrapEnvelopes(t *testing.T) {
+	envelope := `{"statusCode": 201, "body": "hello"}`
+	sandbox := &mockSandbox{invoke: respondWith(envelope)}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+	assert.Equal(t, http.StatusCreated, w.Code, "BUFFERED is the default")
+
+	t.Setenv(invokeModeEnvKey, invokeModeResponseStream)
+	w = directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, envelope, w.Body.String())
+}
+
+func TestDirectInvokeBufferedModeDoesNotStream(t *testing.T) {
+	sandbox := &mockSandbox{invoke: streamHTTPIntegrationResponse(`{"statusCode": 202}`, "\x00\x00\x00\x00\x00\x00\x00\x00", "body")}
+
+	w := directInvoke(t, sandbox, httptest.NewRequest("GET", "/hello", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "body")
+	assert.False(t, w.Flushed, "the response is sent once the function returns")
+}
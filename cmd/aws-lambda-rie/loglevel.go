@@ -0,0 +1,75 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the application log level of Lambda's advanced logging controls, which the runtime reads as well
+const functionLogLevelEnvKey = "AWS_LAMBDA_LOG_LEVEL"
+
+// functionLogLevels maps the levels of structured function logs to logrus levels, with the aliases
+// of the runtimes' logging libraries
+var functionLogLevels = map[string]log.Level{
+	"trace":    log.TraceLevel,
+	"debug":    log.DebugLevel,
+	"info":     log.InfoLevel,
+	"warn":     log.WarnLevel,
+	"warning":  log.WarnLevel,
+	"error":    log.ErrorLevel,
+	"critical": log.FatalLevel,
+	"fatal":    log.FatalLevel,
+}
+
+type functionLogRecord struct {
+	Level string `json:"level"`
+}
+
+// functionLogLevel reads AWS_LAMBDA_LOG_LEVEL, function logs are not filtered when it is not set or invalid
+func functionLogLevel() (log.Level, bool) {
+	configured := GetenvWithDefault(functionLogLevelEnvKey, "")
+	if configured == "" {
+		return 0, false
+	}
+	level, found := functionLogLevels[strings.ToLower(configured)]
+	if !found {
+		log.Warnf("Invalid %s %q, function logs are not filtered", functionLogLevelEnvKey, configured)
+		return 0, false
+	}
+	return level, true
+}
+
+// filterLogLevel drops the lines of a function log write that are JSON records with a level below threshold,
+// like Lambda does for structured logs. Plain text lines and records without a known level are kept.
+func filterLogLevel(p []byte, threshold log.Level) []byte {
+	lines := bytes.SplitAfter(p, []byte("\n"))
+	kept := lines[:0]
+	for _, line := range lines {
+		if lineLevel, found := logLineLevel(line); !found || lineLevel <= threshold {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == len(lines) {
+		return p
+	}
+	return bytes.Join(kept, nil)
+}
+
+func logLineLevel(line []byte) (log.Level, bool) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return 0, false
+	}
+	var record functionLogRecord
+	if json.Unmarshal(trimmed, &record) != nil {
+		return 0, false
+	}
+	level, found := functionLogLevels[strings.ToLower(record.Level)]
+	return level, found
+}
@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctionLogLevel(t *testing.T) {
+	_, filtered := functionLogLevel()
+	assert.False(t, filtered, "function logs are not filtered by default")
+	t.Setenv(functionLogLevelEnvKey, "verbose")
+	_, filtered = functionLogLevel()
+	assert.False(t, filtered)
+
+	t.Setenv(functionLogLevelEnvKey, "WARN")
+	level, filtered := functionLogLevel()
+	assert.True(t, filtered)
+	assert.Equal(t, log.WarnLevel, level)
+}
+
+func TestFilterFunctionLogLevel(t *testing.T) {
+	t.Setenv(functionLogLevelEnvKey, "WARN")
+	logs := newInvocationLogs(1)
+	capture := logs.begin()
+	functionLog, _, _ := logs.GetRuntimeSockets()
+
+	write := `{"level": "DEBUG", "message": "a"}` + "\n" +
+		`{"level": "WARN", "message": "b"}` + "\n" +
+		`{"level": "info", "message": "c"}` + "\n" +
+		"plain DEBUG line\n" +
+		`{"level": "CRITICAL", "message": "d"}` + "\n" +
+		`{"message": "no level"}` + "\n"
+	written, err := functionLog.Write([]byte(write))
+	assert.NoError(t, err)
+	assert.Equal(t, len(write), written)
+	written, err = functionLog.Write([]byte(`{"level": "TRACE", "message": "e"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, len(`{"level": "TRACE", "message": "e"}`+"\n"), written, "a dropped write is still reported as written")
+
+	logs.end(capture, "request")
+	events, _ := logs.get("request")
+	var records []string
+	for _, event := range events {
+		records = append(records, event.Record)
+	}
+	assert.Equal(t, []string{
+		`{"level": "WARN", "message": "b"}`,
+		"plain DEBUG line",
+		`{"level": "CRITICAL", "message": "d"}`,
+		`{"message": "no level"}`,
+	}, records)
+}
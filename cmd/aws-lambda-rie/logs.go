@@ -0,0 +1,297 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+)
+
+const (
+	logRetentionEnvKey  = "AWS_LAMBDA_RIE_LOG_RETENTION"
+	defaultLogRetention = 20
+
+	logSourcePlatform  = "platform"
+	logSourceFunction  = "function"
+	logSourceExtension = "extension"
+
+	logTypeHeader   = "X-Amz-Log-Type"
+	logTypeTail     = "Tail"
+	logResultHeader = "X-Amz-Log-Result"
+	logTailBytes    = 4 * 1024
+)
+
+// platformLog receives the START, END and REPORT lines, main points it
+// to the log capture so that they are kept along with the function logs
+var platformLog io.Writer = os.Stdout
+
+type logEvent struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Record string    `json:"record"`
+}
+
+type logCapture struct {
+	events []logEvent
+	// the request ID of the invoke the capture is for, set by InvokeHandler with attributeLogs
+	requestID string
+}
+
+// invocationLogs tees the runtime and extension output to stdout and keeps the
+// lines written while an invoke is in flight, indexed by its request ID
+type invocationLogs struct {
+	mutex     sync.Mutex
+	retention int
+	active    map[*logCapture]struct{}
+	captured  map[string][]logEvent
+	order     []string
+	// the request ID rapid set as the current one when it started the last invoke
+	current string
+}
+
+func newInvocationLogs(retention int) *invocationLogs {
+	return &invocationLogs{
+		retention: retention,
+		active:    map[*logCapture]struct{}{},
+		captured:  map[string][]logEvent{},
+	}
+}
+
+func newInvocationLogsFromEnv() *invocationLogs {
+	retention, err := strconv.Atoi(GetenvWithDefault(logRetentionEnvKey, strconv.Itoa(defaultLogRetention)))
+	if err != nil || retention < 0 {
+		log.Warnf("Invalid %s, using default of %d", logRetentionEnvKey, defaultLogRetention)
+		retention = defaultLogRetention
+	}
+	return newInvocationLogs(retention)
+}
+
+// GetRuntimeSockets is called whenever the runtime starts, which is when AWS_LAMBDA_LOG_LEVEL is read
+func (l *invocationLogs) GetRuntimeSockets() (io.Writer, io.Writer, error) {
+	stream := &logStream{logs: l, source: logSourceFunction}
+	stream.threshold, stream.filterLevels = functionLogLevel()
+	return stream, stream, nil
+}
+
+func (l *invocationLogs) GetExtensionSockets() (io.Writer, io.Writer, error) {
+	stream := l.stream(logSourceExtension)
+	return stream, stream, nil
+}
+
+var _ telemetry.StdLogsEgressAPI = (*invocationLogs)(nil)
+
+// stream returns a writer for one log source. Output is always forwarded to
+// stdout, which is where the emulator logged everything before capture existed.
+func (l *invocationLogs) stream(source string) io.Writer {
+	return &logStream{logs: l, source: source}
+}
+
+func (l *invocationLogs) append(source string, p []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// captures are kept even without retention, they also make the tail of the logs
+	if len(l.active) == 0 || len(p) == 0 {
+		return
+	}
+
+	// the lines go to the capture of the current invoke. When there is none, e.g. during the init of a cold start,
+	// which runs before rapid starts the invoke, they go to every active capture.
+	var captures []*logCapture
+	for capture := range l.active {
+		if capture.requestID != "" && capture.requestID == l.current {
+			captures = append(captures, capture)
+		}
+	}
+	if len(captures) == 0 {
+		for capture := range l.active {
+			captures = append(captures, capture)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		event := logEvent{Time: now, Source: source, Record: line}
+		for _, capture := range captures {
+			capture.events = append(capture.events, event)
+		}
+	}
+}
+
+func (l *invocationLogs) setCurrentRequestID(requestID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.current = requestID
+}
+
+// attribute makes the capture receive the lines written while rapid runs the invoke with requestID
+func (l *invocationLogs) attribute(capture *logCapture, requestID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	capture.requestID = requestID
+}
+
+// logAttribution is the events API given to rapid, it tells the invocation logs which invoke is the current one
+// and forwards the events to platformEvents
+type logAttribution struct {
+	interop.EventsAPI
+	logs *invocationLogs
+}
+
+func (a *logAttribution) SetCurrentRequestID(requestID interop.RequestID) {
+	a.logs.setCurrentRequestID(string(requestID))
+	a.EventsAPI.SetCurrentRequestID(requestID)
+}
+
+type logCaptureRef struct {
+	logs    *invocationLogs
+	capture *logCapture
+}
+
+// attributeLogs attributes the logs captured for the request, if any, to the invoke with requestID
+func attributeLogs(ctx context.Context, requestID string) {
+	if ref, ok := ctx.Value(logCaptureKey).(logCaptureRef); ok {
+		ref.logs.attribute(ref.capture, requestID)
+	}
+}
+
+func (l *invocationLogs) begin() *logCapture {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	capture := &logCapture{}
+	l.active[capture] = struct{}{}
+	return capture
+}
+
+// end stops the capture and, if the invoke got a request ID, retains its events
+func (l *invocationLogs) end(capture *logCapture, requestID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.active, capture)
+	if requestID == "" || l.retention == 0 {
+		return
+	}
+
+	l.captured[requestID] = capture.events
+	l.order = append(l.order, requestID)
+	for len(l.order) > l.retention {
+		delete(l.captured, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+// tail returns the last 4 KB of the logs captured so far, as the Invoke API returns them in X-Amz-Log-Result
+func (l *invocationLogs) tail(capture *logCapture) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var text strings.Builder
+	for _, event := range capture.events {
+		text.WriteString(event.Record + "\n")
+	}
+	logs := text.String()
+	if len(logs) > logTailBytes {
+		logs = logs[len(logs)-logTailBytes:]
+	}
+	return base64.StdEncoding.EncodeToString([]byte(logs))
+}
+
+func (l *invocationLogs) get(requestID string) ([]logEvent, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	events, found := l.captured[requestID]
+	return events, found
+}
+
+type logStream struct {
+	logs   *invocationLogs
+	source string
+	// structured records below threshold are dropped when filterLevels is set
+	threshold    log.Level
+	filterLevels bool
+}
+
+func (s *logStream) Write(p []byte) (int, error) {
+	kept := p
+	if s.filterLevels {
+		if kept = filterLogLevel(p, s.threshold); len(kept) == 0 {
+			return len(p), nil
+		}
+	}
+	redacted := redactLines(kept)
+	s.logs.append(s.source, redacted)
+	if _, err := os.Stdout.Write(redacted); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// captureLogs attributes the logs written while the request is served to the
+// request ID set by InvokeHandler, see attributeLogs
+func captureLogs(logs *invocationLogs) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			capture := logs.begin()
+			if r.Header.Get(logTypeHeader) == logTypeTail {
+				w = &tailLogWriter{ResponseWriter: w, logs: logs, capture: capture}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), logCaptureKey, logCaptureRef{logs, capture})))
+			logs.end(capture, w.Header().Get(requestIDHeader))
+		})
+	}
+}
+
+// tailLogWriter adds the tail of the invoke's logs to the response headers when they are sent, which
+// InvokeHandler does once the REPORT line is written. It is not a Flusher, so tailed responses are
+// buffered rather than streamed: the logs are only complete once the function has returned.
+type tailLogWriter struct {
+	http.ResponseWriter
+	logs        *invocationLogs
+	capture     *logCapture
+	wroteHeader bool
+}
+
+func (t *tailLogWriter) WriteHeader(statusCode int) {
+	if !t.wroteHeader {
+		t.wroteHeader = true
+		t.Header().Set(logResultHeader, t.logs.tail(t.capture))
+	}
+	t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *tailLogWriter) Write(p []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+func InvocationLogsHandler(w http.ResponseWriter, r *http.Request, logs *invocationLogs) {
+	requestID := chi.URLParam(r, "id")
+	events, found := logs.get(requestID)
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "ResourceNotFound", "No logs captured for request ID "+requestID)
+		return
+	}
+
+	if events == nil {
+		events = []logEvent{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"requestId": requestID, "logs": events})
+}
@@ -0,0 +1,124 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureLogsPerInvocation(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	logs := newInvocationLogs(1)
+	platformLog = logs.stream(logSourcePlatform)
+	t.Cleanup(func() { platformLog = os.Stdout })
+	functionLog, _, _ := logs.GetRuntimeSockets()
+
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		functionLog.Write([]byte("hello from " + i.ID + "\n"))
+		w.Write([]byte(`"ok"`))
+		return nil
+	}}
+	handler := captureLogs(logs)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, newInvokeRequest("{}"))
+	firstID := first.Header().Get(requestIDHeader)
+
+	events, found := logs.get(firstID)
+	assert.True(t, found)
+	var sources, records []string
+	for _, event := range events {
+		sources = append(sources, event.Source)
+		records = append(records, event.Record)
+	}
+	// INIT_START, START, the function's line, END and REPORT
+	assert.Equal(t, []string{logSourcePlatform, logSourcePlatform, logSourceFunction, logSourcePlatform, logSourcePlatform}, sources)
+	assert.Equal(t, "hello from "+firstID, records[2])
+
+	functionLog.Write([]byte("between invokes\n"))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, newInvokeRequest("{}"))
+
+	_, found = logs.get(firstID)
+	assert.False(t, found, "retention is bounded to one invocation")
+	events, found = logs.get(second.Header().Get(requestIDHeader))
+	assert.True(t, found)
+	for _, event := range events {
+		assert.NotEqual(t, "between invokes", event.Record)
+	}
+}
+
+func TestCaptureLogsTail(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	logs := newInvocationLogs(0)
+	platformLog = logs.stream(logSourcePlatform)
+	t.Cleanup(func() { platformLog = os.Stdout })
+	functionLog, _, _ := logs.GetRuntimeSockets()
+
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		functionLog.Write([]byte(strings.Repeat("x", logTailBytes) + "\n"))
+		functionLog.Write([]byte("last line\n"))
+		w.Write([]byte(`"ok"`))
+		return nil
+	}}
+	handler := captureLogs(logs)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	}))
+
+	req := newInvokeRequest("{}")
+	req.Header.Set(logTypeHeader, logTypeTail)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, `"ok"`, w.Body.String())
+	tail, err := base64.StdEncoding.DecodeString(w.Header().Get(logResultHeader))
+	assert.NoError(t, err)
+	assert.Len(t, tail, logTailBytes)
+	assert.Contains(t, string(tail), "last line\nEND RequestId: "+w.Header().Get(requestIDHeader))
+	assert.Contains(t, string(tail), "REPORT RequestId: ")
+
+	untailed := httptest.NewRecorder()
+	handler.ServeHTTP(untailed, newInvokeRequest("{}"))
+	assert.Empty(t, untailed.Header().Get(logResultHeader))
+}
+
+func TestCaptureLogsOfTheCurrentInvoke(t *testing.T) {
+	logs := newInvocationLogs(2)
+	events := &logAttribution{EventsAPI: &telemetry.NoOpEventsAPI{}, logs: logs}
+	functionLog, _, _ := logs.GetRuntimeSockets()
+
+	running, rejected := logs.begin(), logs.begin()
+	functionLog.Write([]byte("init\n"))
+	logs.attribute(running, "running")
+	logs.attribute(rejected, "rejected")
+	events.SetCurrentRequestID("running")
+	functionLog.Write([]byte("invoke\n"))
+	logs.end(running, "running")
+	logs.end(rejected, "rejected")
+
+	records := func(requestID string) []string {
+		var records []string
+		captured, _ := logs.get(requestID)
+		for _, event := range captured {
+			records = append(records, event.Record)
+		}
+		return records
+	}
+	assert.Equal(t, []string{"init", "invoke"}, records("running"))
+	assert.Equal(t, []string{"init"}, records("rejected"), "the lines of another invoke are not captured")
+}
@@ -9,6 +9,8 @@ import (
 	"net"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"go.amzn.com/lambda/interop"
@@ -20,14 +22,32 @@ import (
 const (
 	optBootstrap     = "/opt/bootstrap"
 	runtimeBootstrap = "/var/runtime/bootstrap"
+
+	defaultEmulatorPort = "8080"
+	allowRemoteEnvKey   = "AWS_LAMBDA_RIE_ALLOW_REMOTE"
+	startupDelayEnvKey  = "AWS_LAMBDA_RIE_STARTUP_DELAY_MS"
+
+	extensionsTimeoutEnvKey = "AWS_LAMBDA_RIE_EXTENSIONS_TIMEOUT_MS"
 )
 
 type options struct {
 	LogLevel           string `long:"log-level" description:"The level of AWS Lambda Runtime Interface Emulator logs to display. Can also be set by the environment variable 'LOG_LEVEL'. Defaults to the value 'info'."`
 	InitCachingEnabled bool   `long:"enable-init-caching" description:"Enable support for Init Caching"`
 	// Do not have a default value so we do not need to keep it in sync with the default value in lambda/rapidcore/sandbox_builder.go
-	RuntimeAPIAddress               string `long:"runtime-api-address" description:"The address of the AWS Lambda Runtime API to communicate with the Lambda execution environment."`
-	RuntimeInterfaceEmulatorAddress string `long:"runtime-interface-emulator-address" default:"0.0.0.0:8080" description:"The address for the AWS Lambda Runtime Interface Emulator to accept HTTP request upon."`
+	RuntimeAPIAddress               string        `long:"runtime-api-address" description:"The address of the AWS Lambda Runtime API to communicate with the Lambda execution environment."`
+	RuntimeInterfaceEmulatorAddress string        `long:"runtime-interface-emulator-address" description:"The address for the AWS Lambda Runtime Interface Emulator to accept HTTP request upon. Defaults to '127.0.0.1:8080', or '0.0.0.0:8080' with --allow-remote."`
+	AllowRemote                     bool          `long:"allow-remote" description:"Accept HTTP requests on all interfaces by default. Can also be set by the environment variable 'AWS_LAMBDA_RIE_ALLOW_REMOTE=true'."`
+	Host                            string        `long:"host" description:"The host of the address the AWS Lambda Runtime Interface Emulator listens on, keeping the default or given port."`
+	Port                            string        `long:"port" description:"The port the AWS Lambda Runtime Interface Emulator listens on, keeping the default or given host."`
+	Listen                          string        `long:"listen" description:"The full host:port address the AWS Lambda Runtime Interface Emulator listens on. Takes precedence over the other address options."`
+	ShutdownTimeout                 time.Duration `long:"shutdown-timeout" default:"10s" description:"How long invokes in flight are given to complete on SIGINT or SIGTERM before their connections are closed."`
+	MaxPayloadBytes                 int64         `long:"max-payload-bytes" default:"6291456" description:"The largest invoke payload accepted, larger ones are rejected with a 413 like Lambda does. Defaults to Lambda's 6 MB limit of synchronous invokes."`
+	MaxResponseBytes                int64         `long:"max-response-bytes" default:"6291456" description:"The largest function response returned, larger ones are replaced by a 502 Function.ResponseSizeTooLarge error. Defaults to Lambda's 6 MB limit of synchronous invokes."`
+	TLSCert                         string        `long:"tls-cert" description:"The certificate file to serve HTTPS with, along with --tls-key."`
+	TLSKey                          string        `long:"tls-key" description:"The private key file of --tls-cert."`
+	TLSSelfSigned                   bool          `long:"tls-self-signed" description:"Serve HTTPS with a certificate for localhost generated at startup."`
+	ResetPerInvoke                  bool          `long:"reset-per-invoke" description:"Reset the sandbox before every invoke, so that each one starts cold and initializes the function again. Can also be set by the environment variable 'AWS_LAMBDA_RIE_RESET=1'."`
+	LogFormat                       string        `long:"log-format" default:"text" choice:"text" choice:"json" description:"The format of the platform logs: text for the START, END and REPORT lines, json for Lambda's JSON platform records."`
 }
 
 func main() {
@@ -57,34 +77,170 @@ func main() {
 		}
 	}
 
-	_, _, err := net.SplitHostPort(opts.RuntimeInterfaceEmulatorAddress)
-
+	address, err := emulatorAddress(opts)
 	if err != nil {
-		log.WithError(err).Fatalf("The command line value for \"--runtime-interface-emulator-address\" is not a valid network address %q.", opts.RuntimeInterfaceEmulatorAddress)
+		log.WithError(err).Fatal("Invalid address for the AWS Lambda Runtime Interface Emulator.")
+	}
+	opts.RuntimeInterfaceEmulatorAddress = address
+	host, _, _ := net.SplitHostPort(address)
+
+	if isPublicBind(host) {
+		log.Warnf("Listening on all interfaces (%s): the invoke endpoint is unauthenticated and reachable from other hosts", opts.RuntimeInterfaceEmulatorAddress)
+	}
+
+	if delay := startupDelay(); delay > 0 {
+		log.Infof("Waiting %s before starting, as set by %s, e.g. for dependent containers to come up", delay, startupDelayEnvKey)
+		time.Sleep(delay)
 	}
 
+	platformLogFormat = opts.LogFormat
+	if opts.MaxPayloadBytes <= 0 {
+		log.Fatalf("The command line value for \"--max-payload-bytes\" must be positive, got %d.", opts.MaxPayloadBytes)
+	}
+	maxPayloadBytes = opts.MaxPayloadBytes
+	if opts.MaxResponseBytes <= 0 {
+		log.Fatalf("The command line value for \"--max-response-bytes\" must be positive, got %d.", opts.MaxResponseBytes)
+	}
+	maxResponseBytes = opts.MaxResponseBytes
+	resetPerInvoke = opts.ResetPerInvoke
 	bootstrap, handler := getBootstrap(args, opts)
-	sandbox := rapidcore.
-		NewSandboxBuilder().
+	specs, err := parseFunctions(GetenvWithDefault(functionsEnvKey, ""))
+	if err != nil {
+		log.WithError(err).Fatalf("Invalid %s", functionsEnvKey)
+	}
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid TLS options.")
+	}
+	functions, err := startFunctions(specs, args, tlsConfig != nil)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start the functions")
+	}
+	logs := newInvocationLogsFromEnv()
+	platformLog = logs.stream(logSourcePlatform)
+	sandbox := rapidcore.NewSandboxBuilder()
+	shutdown := newGracefulShutdown(sandbox.DefaultInteropServer(), opts.ShutdownTimeout)
+	sandbox.
+		AddDrainFunc(shutdown.drain).
+		AddDrainFunc(functions.stop).
 		AddShutdownFunc(context.CancelFunc(func() { os.Exit(0) })).
 		SetExtensionsFlag(true).
+		SetTracer(newTraceForwardingTracer()).
+		SetLogsEgressAPI(logs).
+		SetEventsAPI(&logAttribution{EventsAPI: platformEvents, logs: logs}).
+		SetExtensionsReadyTimeout(extensionsTimeout()).
 		SetInitCachingFlag(opts.InitCachingEnabled)
 
-	if len(handler) > 0 {
-		sandbox.SetHandler(handler)
+	positionalHandler = handler
+	if resolved, source, err := resolveHandler(positionalHandler); err == nil {
+		log.Infof("Using handler %q (from %s)", resolved, source)
+		sandbox.SetHandler(resolved)
+	} else {
+		log.Warn(noHandlerHelp)
+	}
+
+	var chaos *runtimeAPIChaos
+	if chaosEnabled() {
+		log.Warn("Chaos mode is enabled, faults can be injected in the Runtime API through the admin API")
+		chaos = newRuntimeAPIChaos()
+		sandbox.SetRuntimeAPIMiddleware(chaos.middleware)
 	}
 
 	if opts.RuntimeAPIAddress != "" {
 		sandbox.SetRuntimeAPIAddress(opts.RuntimeAPIAddress)
 	}
 
-	sandboxContext, internalStateFn := sandbox.Create()
+	sandboxContext, internalStateFn, err := sandbox.Create()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start the sandbox")
+	}
 	// Since we have not specified a custom interop server for standalone, we can
 	// directly reference the default interop server, which is a concrete type
 	sandbox.DefaultInteropServer().SetSandboxContext(sandboxContext)
 	sandbox.DefaultInteropServer().SetInternalStateGetter(internalStateFn)
+	if neverReset() {
+		log.Warnf("%s is set, invokes that time out do not reset the sandbox, POST %s/reset to recover a stuck runtime", neverResetEnvKey, adminPathPrefix)
+		sandbox.DefaultInteropServer().DisableTimeoutReset()
+	}
+
+	startHTTPServer(opts.RuntimeInterfaceEmulatorAddress, sandbox, bootstrap, logs, chaos, shutdown, functions, tlsConfig)
+}
+
+// emulatorAddress combines the address options: --listen wins, otherwise --host and --port
+// replace the parts of --runtime-interface-emulator-address or of the default address
+func emulatorAddress(opts options) (string, error) {
+	address := opts.Listen
+	option := "--listen"
+	if address == "" {
+		address = opts.RuntimeInterfaceEmulatorAddress
+		option = "--runtime-interface-emulator-address"
+	}
+	if address == "" {
+		address = defaultEmulatorAddress(opts.AllowRemote || os.Getenv(allowRemoteEnvKey) == "true")
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("the value for %q is not a valid network address %q: %s", option, address, err)
+	}
+	if opts.Listen == "" {
+		if opts.Host != "" {
+			host = opts.Host
+		}
+		if opts.Port != "" {
+			port = opts.Port
+			option = "--port"
+		}
+	}
+
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		return "", fmt.Errorf("the value for %q has an invalid port %q, it must be between 1 and 65535", option, port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// defaultEmulatorAddress only binds localhost unless remote access was explicitly allowed
+func defaultEmulatorAddress(allowRemote bool) string {
+	if allowRemote {
+		return "0.0.0.0:" + defaultEmulatorPort
+	}
+	return "127.0.0.1:" + defaultEmulatorPort
+}
+
+// startupDelay is how long to wait before starting the Runtime API and accepting invokes
+func startupDelay() time.Duration {
+	configured := GetenvWithDefault(startupDelayEnvKey, "")
+	if configured == "" {
+		return 0
+	}
+
+	delayMs, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || delayMs < 0 {
+		log.Warnf("Invalid %s %q, starting without delay", startupDelayEnvKey, configured)
+		return 0
+	}
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+// extensionsTimeout is how long an invoke waits for the extensions to call next once the function
+// responded, zero leaves only the function timeout
+func extensionsTimeout() time.Duration {
+	configured := GetenvWithDefault(extensionsTimeoutEnvKey, "")
+	if configured == "" {
+		return 0
+	}
+
+	timeoutMs, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || timeoutMs <= 0 {
+		log.Warnf("Invalid %s %q, extensions are only bound by the function timeout", extensionsTimeoutEnvKey, configured)
+		return 0
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
 
-	startHTTPServer(opts.RuntimeInterfaceEmulatorAddress, sandbox, bootstrap)
+func isPublicBind(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
 }
 
 func getCLIArgs() (options, []string) {
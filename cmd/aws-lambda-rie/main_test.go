@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultEmulatorAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8080", defaultEmulatorAddress(false))
+	assert.Equal(t, "0.0.0.0:8080", defaultEmulatorAddress(true))
+}
+
+func TestIsPublicBind(t *testing.T) {
+	assert.True(t, isPublicBind(""))
+	assert.True(t, isPublicBind("0.0.0.0"))
+	assert.True(t, isPublicBind("::"))
+	assert.False(t, isPublicBind("127.0.0.1"))
+	assert.False(t, isPublicBind("localhost"))
+}
+
+func TestStartupDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), startupDelay())
+	t.Setenv(startupDelayEnvKey, "1500")
+	assert.Equal(t, 1500*time.Millisecond, startupDelay())
+	t.Setenv(startupDelayEnvKey, "-1")
+	assert.Equal(t, time.Duration(0), startupDelay())
+	t.Setenv(startupDelayEnvKey, "soon")
+	assert.Equal(t, time.Duration(0), startupDelay())
+}
+
+func TestExtensionsTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), extensionsTimeout())
+	t.Setenv(extensionsTimeoutEnvKey, "250")
+	assert.Equal(t, 250*time.Millisecond, extensionsTimeout())
+	t.Setenv(extensionsTimeoutEnvKey, "0")
+	assert.Equal(t, time.Duration(0), extensionsTimeout())
+}
+
+func TestEmulatorAddress(t *testing.T) {
+	for _, test := range []struct {
+		opts     options
+		expected string
+	}{
+		{options{}, "127.0.0.1:8080"},
+		{options{AllowRemote: true}, "0.0.0.0:8080"},
+		{options{Port: "9000"}, "127.0.0.1:9000"},
+		{options{Host: "0.0.0.0", Port: "9000"}, "0.0.0.0:9000"},
+		{options{RuntimeInterfaceEmulatorAddress: "10.0.0.1:7000", Port: "9000"}, "10.0.0.1:9000"},
+		{options{Listen: "[::1]:9100", Host: "0.0.0.0", Port: "9000"}, "[::1]:9100"},
+	} {
+		address, err := emulatorAddress(test.opts)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, address)
+	}
+
+	for _, opts := range []options{{Port: "0"}, {Port: "70000"}, {Port: "http"}, {Listen: "localhost"}, {Listen: "localhost:-1"}} {
+		_, err := emulatorAddress(opts)
+		assert.Error(t, err, "%+v", opts)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// cgroupMemoryFiles hold the memory usage of the container, which the emulator shares with the runtime and
+// extensions, in order of preference: the peaks of cgroup v2 and v1, then the current usage of older v2 kernels
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.peak",
+	"/sys/fs/cgroup/memory/memory.max_usage_in_bytes",
+	"/sys/fs/cgroup/memory.current",
+}
+
+// readPeakMemoryMB returns the peak memory used in the container in MB, and false where cgroups are
+// not available (e.g. on macOS). Like Lambda's Max Memory Used, it is the peak of the environment so far.
+func readPeakMemoryMB() (int64, bool) {
+	for _, file := range cgroupMemoryFiles {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		bytes, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+		if err != nil || bytes <= 0 {
+			continue
+		}
+		return (bytes + 1<<20 - 1) >> 20, true
+	}
+	return 0, false
+}
+
+// maxMemoryUsed is the Max Memory Used of the REPORT line, the configured memory size when the peak is unknown
+func maxMemoryUsed(memorySize string) string {
+	if peakMB, ok := readPeakMemoryMB(); ok {
+		return strconv.FormatInt(peakMB, 10)
+	}
+	return memorySize
+}
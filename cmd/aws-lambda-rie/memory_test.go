@@ -0,0 +1,37 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPeakMemoryMB(t *testing.T) {
+	dir := t.TempDir()
+	peak := filepath.Join(dir, "memory.peak")
+	current := filepath.Join(dir, "memory.current")
+	files := cgroupMemoryFiles
+	cgroupMemoryFiles = []string{peak, current}
+	t.Cleanup(func() { cgroupMemoryFiles = files })
+
+	_, ok := readPeakMemoryMB()
+	assert.False(t, ok)
+	assert.Equal(t, "128", maxMemoryUsed("128"), "the memory size is reported without cgroups")
+
+	require.NoError(t, ioutil.WriteFile(current, []byte("1048576\n"), 0644))
+	peakMB, ok := readPeakMemoryMB()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), peakMB)
+
+	require.NoError(t, ioutil.WriteFile(peak, []byte("52428801\n"), 0644))
+	assert.Equal(t, "51", maxMemoryUsed("128"), "the peak is preferred and rounded up to the next MB")
+
+	require.NoError(t, ioutil.WriteFile(peak, []byte("max\n"), 0644))
+	assert.Equal(t, "1", maxMemoryUsed("128"), "unreadable values are skipped")
+}
@@ -0,0 +1,41 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	neverResetEnvKey     = "AWS_LAMBDA_RIE_NEVER_RESET"
+	manualResetReason    = "manual"
+	manualResetTimeoutMs = 2000
+	resetFailedType      = "ResetFailed"
+)
+
+// neverReset tells whether AWS_LAMBDA_RIE_NEVER_RESET keeps the sandbox warm for the whole session: timeouts
+// do not reset it and no cold starts are injected. A runtime that exited or failed to initialize is still reset,
+// it cannot serve another invoke otherwise.
+func neverReset() bool {
+	return GetenvWithDefault(neverResetEnvKey, "false") == "true"
+}
+
+// ResetHandler resets the sandbox on request, the next invoke starts cold. With AWS_LAMBDA_RIE_NEVER_RESET
+// it is how a runtime stuck in an invoke that timed out is recovered.
+func ResetHandler(w http.ResponseWriter, r *http.Request, reset resetFunc) {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
+	log.Info("Resetting the sandbox on request")
+	description, err := reset(manualResetReason, manualResetTimeoutMs)
+	if err != nil {
+		log.Errorf("Reset failed: %s", err)
+		writeJSONError(w, http.StatusBadGateway, resetFailedType, err.Error())
+		return
+	}
+	initDone = false
+	writeJSON(w, http.StatusOK, description)
+}
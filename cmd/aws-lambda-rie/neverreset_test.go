@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/core/statejson"
+)
+
+func TestResetHandler(t *testing.T) {
+	var reasons []string
+	fail := false
+	reset := func(reason string, timeoutMs int64) (*statejson.ResetDescription, error) {
+		reasons = append(reasons, reason)
+		if fail {
+			return nil, errors.New("sandbox gone")
+		}
+		return &statejson.ResetDescription{ExtensionsResetMs: 3}, nil
+	}
+	initDone = true
+	t.Cleanup(func() { initDone = false })
+
+	w := httptest.NewRecorder()
+	ResetHandler(w, httptest.NewRequest("POST", "/_rie/reset", nil), reset)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"extensionsResetMs":3`)
+	assert.False(t, initDone, "the next invoke starts cold")
+
+	initDone = true
+	fail = true
+	w = httptest.NewRecorder()
+	ResetHandler(w, httptest.NewRequest("POST", "/_rie/reset", nil), reset)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), resetFailedType)
+	assert.Equal(t, []string{manualResetReason, manualResetReason}, reasons)
+}
@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pauseMaxWaitEnvKey  = "AWS_LAMBDA_RIE_PAUSE_MAX_WAIT_MS"
+	defaultPauseMaxWait = 30 * time.Second
+)
+
+type pauseState struct {
+	Paused  bool `json:"paused"`
+	Waiting int  `json:"waiting"`
+}
+
+// invokeGate holds incoming invokes while the emulator is paused through the admin API,
+// so that a test orchestrator can freeze it at a known point and let it continue later
+type invokeGate struct {
+	mutex sync.Mutex
+	// nil unless paused, closed on resume to release the waiting invokes
+	resumed chan struct{}
+	waiting int
+}
+
+func newInvokeGate() *invokeGate {
+	return &invokeGate{}
+}
+
+func (g *invokeGate) pause() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *invokeGate) resume() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+func (g *invokeGate) state() pauseState {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return pauseState{Paused: g.resumed != nil, Waiting: g.waiting}
+}
+
+// enter returns the channel to wait on, nil when not paused
+func (g *invokeGate) enter() chan struct{} {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.resumed != nil {
+		g.waiting++
+	}
+	return g.resumed
+}
+
+func (g *invokeGate) leave() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.waiting--
+}
+
+func (g *invokeGate) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resumed := g.enter()
+		if resumed == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		maxWait := pauseMaxWait()
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		select {
+		case <-resumed:
+			g.leave()
+			next.ServeHTTP(w, r)
+		case <-timer.C:
+			g.leave()
+			log.Warnf("Rejected %s %s, the emulator stayed paused for more than %s", r.Method, r.URL.Path, maxWait)
+			writeJSONError(w, http.StatusServiceUnavailable, "EmulatorPaused", "The emulator is paused, the invoke waited "+maxWait.String())
+		case <-r.Context().Done():
+			g.leave()
+		}
+	})
+}
+
+func pauseMaxWait() time.Duration {
+	configured := GetenvWithDefault(pauseMaxWaitEnvKey, "")
+	if configured == "" {
+		return defaultPauseMaxWait
+	}
+
+	ms, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || ms <= 0 {
+		log.Warnf("Invalid %s %q, using %s", pauseMaxWaitEnvKey, configured, defaultPauseMaxWait)
+		return defaultPauseMaxWait
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// PauseHandler makes the emulator hold incoming invokes until ResumeHandler is called
+func PauseHandler(w http.ResponseWriter, r *http.Request, gate *invokeGate) {
+	gate.pause()
+	log.Warn("Emulator paused, invokes are held until POST /_rie/resume")
+	writeJSON(w, http.StatusOK, gate.state())
+}
+
+// ResumeHandler releases the invokes held since PauseHandler
+func ResumeHandler(w http.ResponseWriter, r *http.Request, gate *invokeGate) {
+	waiting := gate.state().Waiting
+	gate.resume()
+	log.Infof("Emulator resumed, releasing %d held invokes", waiting)
+	writeJSON(w, http.StatusOK, gate.state())
+}
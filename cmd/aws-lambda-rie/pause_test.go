@@ -0,0 +1,54 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseHoldsInvokesUntilResume(t *testing.T) {
+	gate := newInvokeGate()
+	var invoked atomic.Int32
+	handler := gate.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked.Add(1)
+	}))
+
+	PauseHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/_rie/pause", nil), gate)
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newInvokeRequest("{}"))
+		done <- w.Code
+	}()
+
+	assert.Eventually(t, func() bool { return gate.state().Waiting == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(0), invoked.Load())
+
+	ResumeHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/_rie/resume", nil), gate)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, int32(1), invoked.Load())
+	assert.Equal(t, pauseState{}, gate.state())
+}
+
+func TestPausedInvokesGiveUpAfterMaxWait(t *testing.T) {
+	t.Setenv(pauseMaxWaitEnvKey, "20")
+	gate := newInvokeGate()
+	gate.pause()
+	handler := gate.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("held invokes must not run while paused")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 0, gate.state().Waiting)
+	assert.True(t, gate.state().Paused)
+}
@@ -0,0 +1,55 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pingMarkerEnvKey    = "AWS_LAMBDA_RIE_PING_MARKER"
+	pingResponseEnvKey  = "AWS_LAMBDA_RIE_PING_RESPONSE"
+	defaultPingResponse = `"pong"`
+)
+
+// answerPings responds to invokes whose body is exactly AWS_LAMBDA_RIE_PING_MARKER with
+// AWS_LAMBDA_RIE_PING_RESPONSE, without invoking the runtime. It comes before the invocation
+// history, logs and cold starts in the chain so that keep-warm pings leave no trace.
+func answerPings(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		marker := GetenvWithDefault(pingMarkerEnvKey, "")
+		if marker == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// one byte more than the marker is enough to tell, the rest of the body is left unread
+		prefix, err := io.ReadAll(io.LimitReader(r.Body, int64(len(marker))+1))
+		if err != nil {
+			log.Errorf("Failed to read invoke body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if string(prefix) != marker {
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(prefix), r.Body), r.Body}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Debugf("Answering ping on %s", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(GetenvWithDefault(pingResponseEnvKey, defaultPingResponse)))
+	})
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
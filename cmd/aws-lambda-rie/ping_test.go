@@ -0,0 +1,43 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnswerPings(t *testing.T) {
+	var invokedWith []string
+	handler := answerPings(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		invokedWith = append(invokedWith, string(body))
+	}))
+	serve := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newInvokeRequest(body))
+		return w
+	}
+
+	serve(`{"warmer": true}`)
+	assert.Equal(t, []string{`{"warmer": true}`}, invokedWith, "pings are not answered unless a marker is set")
+
+	t.Setenv(pingMarkerEnvKey, `{"warmer": true}`)
+	invokedWith = nil
+	w := serve(`{"warmer": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, defaultPingResponse, w.Body.String())
+	assert.Empty(t, invokedWith)
+
+	serve(`{"warmer": true, "other": 1}`)
+	serve(`{"warmer"`)
+	assert.Equal(t, []string{`{"warmer": true, "other": 1}`, `{"warmer"`}, invokedWith, "other bodies reach the function unchanged")
+
+	t.Setenv(pingResponseEnvKey, `{"status": "warm"}`)
+	assert.Equal(t, `{"status": "warm"}`, serve(`{"warmer": true}`).Body.String())
+}
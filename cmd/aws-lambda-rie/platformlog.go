@@ -0,0 +1,143 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+	// the runtime reads the log format of the function from this variable, as in Lambda
+	logFormatEnvKey = "AWS_LAMBDA_LOG_FORMAT"
+
+	reportStatusSuccess = telemetry.RuntimeDoneSuccess
+	reportStatusError   = telemetry.RuntimeDoneError
+	reportStatusTimeout = "timeout"
+
+	// the span rapid reports for the time the extensions take after the runtime is done
+	extensionOverheadSpan = "extensionOverhead"
+)
+
+// platformLogFormat is set by main from --log-format: text prints the START, END and REPORT lines, json prints
+// Lambda's platform.initStart, platform.start, platform.runtimeDone and platform.report records instead
+var platformLogFormat = logFormatText
+
+// platformEvents receives the events rapid sends through logAttribution, it forwards them to initDurations
+var platformEvents = &platformEventLog{EventsAPI: initDurations}
+
+type platformEvent struct {
+	Time   string      `json:"time"`
+	Type   string      `json:"type"`
+	Record interface{} `json:"record"`
+}
+
+// printPlatformEvent prints a record in the format of Lambda's JSON platform logs
+func printPlatformEvent(eventType string, record interface{}) {
+	event, err := json.Marshal(platformEvent{Time: time.Now().UTC().Format(time.RFC3339Nano), Type: eventType, Record: record})
+	if err != nil {
+		log.Errorf("Failed to marshal %s event: %s", eventType, err)
+		return
+	}
+	fmt.Fprintln(platformLog, string(event))
+}
+
+func printStart(invokeID string, functionVersion string) {
+	if platformLogFormat == logFormatJSON {
+		printPlatformEvent("platform.start", interop.InvokeStartData{RequestID: invokeID, Version: functionVersion})
+		return
+	}
+	fmt.Fprintln(platformLog, "START RequestId: "+invokeID+" Version: "+functionVersion)
+}
+
+// printReportEvent is the platform.report record of printEndReports, without initDurationMs on warm invokes
+func printReportEvent(invokeID string, initDurationMs float64, durationMs float64, memorySize string, status string, spans []interop.Span) {
+	memorySizeMB, _ := strconv.ParseUint(memorySize, 10, 64)
+	maxMemoryUsedMB, _ := strconv.ParseUint(maxMemoryUsed(memorySize), 10, 64)
+	printPlatformEvent("platform.report", interop.ReportData{
+		RequestID: interop.RequestID(invokeID),
+		Status:    status,
+		Metrics: interop.ReportMetrics{
+			DurationMs:       math.Round(durationMs*1000) / 1000,
+			BilledDurationMs: math.Ceil(durationMs),
+			MemorySizeMB:     memorySizeMB,
+			MaxMemoryUsedMB:  maxMemoryUsedMB,
+			InitDurationMs:   math.Round(initDurationMs*1000) / 1000,
+		},
+		Spans: spans,
+	})
+}
+
+// extensionOverheadMs is the duration of the extensionOverhead span, which rapid only reports for
+// invokes with extensions subscribed to INVOKE
+func extensionOverheadMs(spans []interop.Span) (float64, bool) {
+	for _, span := range spans {
+		if span.Name == extensionOverheadSpan {
+			return span.DurationMs, true
+		}
+	}
+	return 0, false
+}
+
+// platformEventLog prints the platform.runtimeDone records in the JSON log format, which rapid
+// only sends to the events API, without their request ID but after setting it as the current one.
+// It also keeps the spans rapid reports for the REPORT of the current invoke.
+type platformEventLog struct {
+	interop.EventsAPI
+
+	mutex     sync.Mutex
+	requestID interop.RequestID
+	spans     []interop.Span
+}
+
+func (l *platformEventLog) SetCurrentRequestID(requestID interop.RequestID) {
+	l.mutex.Lock()
+	if requestID != l.requestID {
+		l.spans = nil
+	}
+	l.requestID = requestID
+	l.mutex.Unlock()
+	l.EventsAPI.SetCurrentRequestID(requestID)
+}
+
+func (l *platformEventLog) SendReportSpan(span interop.Span) error {
+	l.mutex.Lock()
+	l.spans = append(l.spans, span)
+	l.mutex.Unlock()
+	return l.EventsAPI.SendReportSpan(span)
+}
+
+// takeReportSpans returns the spans reported during the invoke, once
+func (l *platformEventLog) takeReportSpans(requestID string) []interop.Span {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if string(l.requestID) != requestID {
+		return nil
+	}
+	spans := l.spans
+	l.spans = nil
+	return spans
+}
+
+func (l *platformEventLog) SendInvokeRuntimeDone(data interop.InvokeRuntimeDoneData) error {
+	if platformLogFormat == logFormatJSON {
+		if data.RequestID == "" {
+			l.mutex.Lock()
+			data.RequestID = l.requestID
+			l.mutex.Unlock()
+		}
+		printPlatformEvent("platform.runtimeDone", data)
+	}
+	return l.EventsAPI.SendInvokeRuntimeDone(data)
+}
@@ -0,0 +1,138 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.amzn.com/lambda/interop"
+)
+
+func jsonPlatformLog(t *testing.T) *bytes.Buffer {
+	var platform bytes.Buffer
+	platformLog = &platform
+	platformLogFormat = logFormatJSON
+	t.Cleanup(func() {
+		platformLog = os.Stdout
+		platformLogFormat = logFormatText
+	})
+	return &platform
+}
+
+func platformRecords(t *testing.T, platform *bytes.Buffer) map[string]map[string]interface{} {
+	records := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(platform.String()), "\n") {
+		var event struct {
+			Time   string                 `json:"time"`
+			Type   string                 `json:"type"`
+			Record map[string]interface{} `json:"record"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		assert.NotEmpty(t, event.Time)
+		records[event.Type] = event.Record
+	}
+	return records
+}
+
+func TestJSONPlatformLogs(t *testing.T) {
+	platform := jsonPlatformLog(t)
+	files := cgroupMemoryFiles
+	cgroupMemoryFiles = nil
+	t.Cleanup(func() { cgroupMemoryFiles = files })
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "orders")
+	t.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "512")
+
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	requestID := w.Header().Get(requestIDHeader)
+	assert.Equal(t, "JSON", sandbox.lastInit.CustomerEnvironmentVariables[logFormatEnvKey])
+
+	records := platformRecords(t, platform)
+	assert.NotContains(t, platform.String(), "START RequestId")
+	assert.Equal(t, "orders", records["platform.initStart"]["functionName"])
+	assert.Equal(t, "on-demand", records["platform.initStart"]["initializationType"])
+	assert.Equal(t, map[string]interface{}{"requestId": requestID, "version": "$LATEST"}, records["platform.start"])
+
+	report := records["platform.report"]
+	require.NotNil(t, report)
+	assert.Equal(t, requestID, report["requestId"])
+	assert.Equal(t, reportStatusSuccess, report["status"])
+	metrics := report["metrics"].(map[string]interface{})
+	assert.Equal(t, float64(512), metrics["memorySizeMB"])
+	assert.Equal(t, float64(512), metrics["maxMemoryUsedMB"])
+	assert.Contains(t, metrics, "durationMs")
+	assert.Contains(t, metrics, "billedDurationMs")
+}
+
+func TestJSONPlatformReportStatus(t *testing.T) {
+	platform := jsonPlatformLog(t)
+
+	sandbox := &mockSandbox{invoke: respondWithFunctionError(`{"errorMessage":"boom"}`)}
+	invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, reportStatusError, platformRecords(t, platform)["platform.report"]["status"])
+}
+
+func TestJSONPlatformRuntimeDone(t *testing.T) {
+	platform := jsonPlatformLog(t)
+
+	events := &platformEventLog{EventsAPI: initDurations}
+	require.NoError(t, events.SendInvokeRuntimeDone(interop.InvokeRuntimeDoneData{
+		RequestID: "req-1",
+		Status:    reportStatusSuccess,
+		Metrics:   &interop.RuntimeDoneInvokeMetrics{ProducedBytes: 4, DurationMs: 1.5},
+	}))
+
+	done := platformRecords(t, platform)["platform.runtimeDone"]
+	assert.Equal(t, "req-1", done["requestId"])
+	assert.Equal(t, map[string]interface{}{"producedBytes": float64(4), "durationMs": 1.5}, done["metrics"])
+
+	platform.Reset()
+	events.SetCurrentRequestID("req-current")
+	require.NoError(t, events.SendInvokeRuntimeDone(interop.InvokeRuntimeDoneData{Status: reportStatusSuccess}))
+	assert.Equal(t, "req-current", platformRecords(t, platform)["platform.runtimeDone"]["requestId"], "rapid leaves it out")
+
+	platformLogFormat = logFormatText
+	platform.Reset()
+	require.NoError(t, events.SendInvokeRuntimeDone(interop.InvokeRuntimeDoneData{RequestID: "req-2"}))
+	assert.Empty(t, platform.String(), "text logs have no runtimeDone line")
+}
+
+// respondWithExtensionOverhead responds like an invoke whose extensions took overheadMs after the runtime
+func respondWithExtensionOverhead(overheadMs float64) func(w http.ResponseWriter, i *interop.Invoke) error {
+	return func(w http.ResponseWriter, i *interop.Invoke) error {
+		platformEvents.SetCurrentRequestID(interop.RequestID(i.ID))
+		platformEvents.SendReportSpan(interop.Span{Name: extensionOverheadSpan, Start: "2026-10-14T07:00:00.000Z", DurationMs: overheadMs})
+		w.Write([]byte(`"ok"`))
+		return nil
+	}
+}
+
+func TestReportExtensionOverhead(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+
+	invoke(t, &mockSandbox{invoke: respondWithExtensionOverhead(12.345)}, newInvokeRequest("{}"))
+	assert.Regexp(t, `REPORT RequestId: \S+\t.*\tMax Memory Used: \d+ MB\tExtension Overhead: 12.35 ms\t\n`, platform.String())
+
+	// the spans of an invoke are not reported again, and invokes without extensions have none
+	platform.Reset()
+	invoke(t, &mockSandbox{invoke: respondWith(`"ok"`)}, newInvokeRequest("{}"))
+	assert.NotContains(t, platform.String(), "Extension Overhead")
+}
+
+func TestJSONPlatformReportExtensionOverhead(t *testing.T) {
+	platform := jsonPlatformLog(t)
+
+	invoke(t, &mockSandbox{invoke: respondWithExtensionOverhead(5)}, newInvokeRequest("{}"))
+	spans := platformRecords(t, platform)["platform.report"]["spans"]
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "extensionOverhead", "start": "2026-10-14T07:00:00.000Z", "durationMs": float64(5)}}, spans)
+}
@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	recoverPanicsEnvKey  = "AWS_LAMBDA_RIE_RECOVER_PANICS"
+	invokePanicErrorType = "ServiceException"
+)
+
+var errInvokePanicked = errors.New("the emulator panicked while processing the invoke")
+
+// invokeRecovering calls sandbox.Invoke and turns a panic of the interop layer into errInvokePanicked,
+// so that one broken invoke does not take the emulator down. AWS_LAMBDA_RIE_RECOVER_PANICS=false lets
+// the panic crash the process instead, which keeps the original stack for debugging.
+func invokeRecovering(sandbox Sandbox, w http.ResponseWriter, invoke *interop.Invoke) (err error) {
+	if GetenvWithDefault(recoverPanicsEnvKey, "true") == "false" {
+		return sandbox.Invoke(w, invoke)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			log.Errorf("Invoke %s panicked: %v\n%s", invoke.ID, p, debug.Stack())
+			err = fmt.Errorf("%w: %v", errInvokePanicked, p)
+		}
+	}()
+	return sandbox.Invoke(w, invoke)
+}
+
+func writeInvokePanic(w http.ResponseWriter, err error) {
+	writeJSONError(w, http.StatusInternalServerError, invokePanicErrorType, err.Error())
+}
@@ -0,0 +1,44 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/interop"
+)
+
+func TestInvokeHandlerRecoversFromPanic(t *testing.T) {
+	panics := true
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		if panics {
+			var resp *interop.StreamableInvokeResponse
+			_ = resp.Headers // malformed runtime response
+		}
+		w.Write([]byte(`"recovered"`))
+		return nil
+	}}
+
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), invokePanicErrorType)
+	assert.Contains(t, w.Body.String(), "nil pointer dereference")
+
+	// the emulator keeps serving invokes
+	panics = false
+	w = invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"recovered"`, w.Body.String())
+}
+
+func TestInvokeHandlerPanicsWhenRecoveryDisabled(t *testing.T) {
+	t.Setenv(recoverPanicsEnvKey, "false")
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		panic("runtime bridge failure")
+	}}
+
+	assert.PanicsWithValue(t, "runtime bridge failure", func() { invoke(t, sandbox, newInvokeRequest("{}")) })
+}
@@ -0,0 +1,121 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	redactKeysEnvKey = "AWS_LAMBDA_RIE_REDACT_KEYS"
+	redactedValue    = "[REDACTED]"
+)
+
+// redactKeys are the lowercased names of AWS_LAMBDA_RIE_REDACT_KEYS, e.g. password,token,secret
+func redactKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(GetenvWithDefault(redactKeysEnvKey, ""), ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// redactPayload masks the values of the JSON keys that contain one of AWS_LAMBDA_RIE_REDACT_KEYS, ignoring case,
+// before a payload is logged or captured. JSON documents held in string values, like the body of an API Gateway
+// event, are redacted as well. Payloads that are not JSON, or have nothing to redact, are returned unchanged.
+func redactPayload(payload []byte) []byte {
+	keys := redactKeys()
+	if len(keys) == 0 {
+		return payload
+	}
+	redacted, changed := redactJSON(payload, keys)
+	if !changed {
+		return payload
+	}
+	return redacted
+}
+
+// redactLines redacts each line of a log write, structured logs have one JSON record per line
+func redactLines(p []byte) []byte {
+	keys := redactKeys()
+	if len(keys) == 0 {
+		return p
+	}
+	lines := bytes.Split(p, []byte("\n"))
+	changed := false
+	for i, line := range lines {
+		if redacted, lineChanged := redactJSON(line, keys); lineChanged {
+			lines[i], changed = redacted, true
+		}
+	}
+	if !changed {
+		return p
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+func redactJSON(document []byte, keys []string) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(document)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return document, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	// numbers are kept as they were written rather than rounded through float64
+	decoder.UseNumber()
+	var value interface{}
+	if decoder.Decode(&value) != nil || decoder.More() {
+		return document, false
+	}
+	value, changed := redactValue(value, keys)
+	if !changed {
+		return document, false
+	}
+
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(value) != nil {
+		return document, false
+	}
+	return bytes.TrimSuffix(encoded.Bytes(), []byte("\n")), true
+}
+
+func redactValue(value interface{}, keys []string) (interface{}, bool) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if matchesRedactKey(name, keys) {
+				v[name], changed = redactedValue, true
+			} else if redacted, fieldChanged := redactValue(field, keys); fieldChanged {
+				v[name], changed = redacted, true
+			}
+		}
+	case []interface{}:
+		for i, element := range v {
+			if redacted, elementChanged := redactValue(element, keys); elementChanged {
+				v[i], changed = redacted, true
+			}
+		}
+	case string:
+		if redacted, stringChanged := redactJSON([]byte(v), keys); stringChanged {
+			return string(redacted), true
+		}
+	}
+	return value, changed
+}
+
+func matchesRedactKey(name string, keys []string) bool {
+	name = strings.ToLower(name)
+	for _, key := range keys {
+		if strings.Contains(name, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPayload(t *testing.T) {
+	payload := []byte(`{"user": "ana", "Password": "hunter2", "auth": {"accessToken": "abc", "scopes": ["read"]}, "items": [{"secret": 1}, {"id": 12345678901234567890}]}`)
+	assert.Equal(t, payload, redactPayload(payload), "nothing is redacted by default")
+
+	t.Setenv(redactKeysEnvKey, " password, TOKEN,secret,")
+	assert.JSONEq(t, `{"user": "ana", "Password": "[REDACTED]", "auth": {"accessToken": "[REDACTED]", "scopes": ["read"]}, "items": [{"secret": "[REDACTED]"}, {"id": 12345678901234567890}]}`, string(redactPayload(payload)))
+
+	// the body of an API Gateway event or response is a JSON document in a string
+	envelope := []byte(`{"statusCode": 200, "body": "{\"token\": \"abc\", \"html\": \"<b>\"}"}`)
+	assert.JSONEq(t, `{"statusCode": 200, "body": "{\"html\":\"<b>\",\"token\":\"[REDACTED]\"}"}`, string(redactPayload(envelope)))
+
+	for _, unchanged := range []string{`"password"`, `not json {"password": "x"}`, `{"user": "ana"}`, `{"password": 1} {"password": 2}`} {
+		assert.Equal(t, unchanged, string(redactPayload([]byte(unchanged))))
+	}
+}
+
+func TestRedactLogs(t *testing.T) {
+	t.Setenv(redactKeysEnvKey, "password")
+	logs := newInvocationLogs(1)
+	capture := logs.begin()
+	functionLog, _, _ := logs.GetRuntimeSockets()
+
+	written, err := functionLog.Write([]byte(`{"level": "INFO", "password": "hunter2"}` + "\nplain password=hunter2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, len(`{"level": "INFO", "password": "hunter2"}`+"\nplain password=hunter2\n"), written)
+
+	logs.end(capture, "request")
+	events, _ := logs.get("request")
+	assert.Equal(t, `{"level":"INFO","password":"[REDACTED]"}`, events[0].Record)
+	assert.Equal(t, "plain password=hunter2", events[1].Record, "only JSON records are redacted")
+}
+
+func TestRedactResponseSink(t *testing.T) {
+	t.Setenv(redactKeysEnvKey, "secret")
+	path := filepath.Join(t.TempDir(), "response.json")
+	t.Setenv(responseSinkEnvKey, path)
+
+	w := invoke(t, &mockSandbox{invoke: respondWith(`{"clientSecret": "s3cr3t"}`)}, newInvokeRequest("{}"))
+	assert.Equal(t, `{"clientSecret": "s3cr3t"}`, w.Body.String(), "the client gets the response unredacted")
+	sunk, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"clientSecret":"[REDACTED]"}`, string(sunk))
+}
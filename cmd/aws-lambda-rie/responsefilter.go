@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	responseFilterEnvKey    = "AWS_LAMBDA_RIE_RESPONSE_FILTER"
+	responseFilterErrorType = "ResponseFilterException"
+	// the filter can tell invokes apart with this variable
+	responseFilterRequestIDEnvKey = "AWS_LAMBDA_RIE_REQUEST_ID"
+	responseFilterTimeoutMsEnvKey = "AWS_LAMBDA_RIE_RESPONSE_FILTER_TIMEOUT_MS"
+	defaultResponseFilterTimeout  = 5 * time.Second
+)
+
+// filterResponse pipes a function response through the program set with AWS_LAMBDA_RIE_RESPONSE_FILTER, which
+// reads the response on stdin and writes the one sent to the client on stdout. The program runs with the
+// emulator's privileges and environment, so it must be trusted, and is killed after
+// AWS_LAMBDA_RIE_RESPONSE_FILTER_TIMEOUT_MS. Without the variable, body is returned as is.
+func filterResponse(ctx context.Context, requestID string, body []byte) ([]byte, error) {
+	filter := GetenvWithDefault(responseFilterEnvKey, "")
+	if filter == "" {
+		return body, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, responseFilterTimeout())
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, filter)
+	cmd.Env = append(os.Environ(), responseFilterRequestIDEnvKey+"="+requestID)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// a program the filter started must not keep the invoke waiting for the pipes once the filter was killed
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", responseFilterTimeout())
+		}
+		return nil, fmt.Errorf("response filter %s failed: %w: %s", filter, err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 {
+		log.Infof("Response filter %s: %s", filter, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// responseFilterTimeout bounds a run of the response filter, AWS_LAMBDA_RIE_RESPONSE_FILTER_TIMEOUT_MS
+func responseFilterTimeout() time.Duration {
+	configured := GetenvWithDefault(responseFilterTimeoutMsEnvKey, "")
+	if configured == "" {
+		return defaultResponseFilterTimeout
+	}
+
+	timeoutMs, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || timeoutMs <= 0 {
+		log.Warnf("Invalid %s %q, using %s", responseFilterTimeoutMsEnvKey, configured, defaultResponseFilterTimeout)
+		return defaultResponseFilterTimeout
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
+
+// writeResponseFilterError logs the path and stderr of the filter, the client only learns that it failed
+func writeResponseFilterError(w http.ResponseWriter, requestID string, err error) {
+	log.Errorf("Invoke %s: %s", requestID, err)
+	writeJSONError(w, http.StatusBadGateway, responseFilterErrorType, "The response filter failed to process the response")
+}
+
+// writeFilteredFunctionError passes the error a function reported through the filter, like its responses
+func writeFilteredFunctionError(w http.ResponseWriter, ctx context.Context, requestID string, body []byte) {
+	filtered, err := filterResponse(ctx, requestID, body)
+	if err != nil {
+		writeResponseFilterError(w, requestID, err)
+		return
+	}
+	writeFunctionError(w, filtered)
+}
@@ -0,0 +1,75 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFilter(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "filter")
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	return path
+}
+
+func TestResponseFilter(t *testing.T) {
+	sandbox := &mockSandbox{invoke: respondWith(`{"password": "hunter2"}`)}
+	assert.Equal(t, `{"password": "hunter2"}`, invoke(t, sandbox, newInvokeRequest("{}")).Body.String(), "responses are not filtered by default")
+
+	t.Setenv(responseFilterEnvKey, writeFilter(t, `sed 's/hunter2/***/'; printf ' %s' "$AWS_LAMBDA_RIE_REQUEST_ID"`))
+	w := invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"password": "***"} `+w.Header().Get(requestIDHeader), w.Body.String())
+}
+
+func TestResponseFilterFailure(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	filter := writeFilter(t, `echo "cannot parse" >&2; exit 3`)
+	t.Setenv(responseFilterEnvKey, filter)
+
+	w := invoke(t, &mockSandbox{invoke: respondWith(`"ok"`)}, newInvokeRequest("{}"))
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), responseFilterErrorType)
+	assert.NotContains(t, w.Body.String(), "cannot parse", "the details are only logged")
+	assert.NotContains(t, w.Body.String(), filter)
+	assert.Contains(t, logs.String(), filter)
+	assert.Contains(t, logs.String(), "exit status 3: cannot parse")
+}
+
+func TestResponseFilterOfFunctionErrors(t *testing.T) {
+	t.Setenv(responseFilterEnvKey, writeFilter(t, `sed 's/hunter2/***/'`))
+
+	w := invoke(t, &mockSandbox{invoke: respondWithFunctionError(`{"errorMessage": "bad password hunter2"}`)}, newInvokeRequest("{}"))
+
+	assert.Equal(t, functionErrorUnhandled, w.Header().Get(functionErrorHeader))
+	assert.JSONEq(t, `{"errorMessage": "bad password ***"}`, w.Body.String())
+}
+
+func TestResponseFilterTimeout(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Setenv(responseFilterEnvKey, writeFilter(t, `sleep 10`))
+	t.Setenv(responseFilterTimeoutMsEnvKey, "50")
+
+	start := time.Now()
+	w := invoke(t, &mockSandbox{invoke: respondWith(`"ok"`)}, newInvokeRequest("{}"))
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, logs.String(), "timed out after 50ms")
+}
@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	rootBehaviorEnvKey = "AWS_LAMBDA_RIE_ROOT_BEHAVIOR"
+	rootBehaviorInvoke = "invoke"
+	rootBehaviorInfo   = "info"
+)
+
+type rootInfo struct {
+	Status      string `json:"status"`
+	FunctionArn string `json:"functionArn"`
+	Runtime     string `json:"runtime"`
+	InvokePath  string `json:"invokePath"`
+}
+
+// answerRootInfo responds to a bare GET / with a description of the emulator instead of
+// invoking the function when AWS_LAMBDA_RIE_ROOT_BEHAVIOR is info, so that browser checks and
+// health probes do not trigger invokes. Like answerPings, it comes first in the chain.
+func answerRootInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/" || rootBehavior() != rootBehaviorInfo {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rootInfo{
+			Status:      "ok",
+			FunctionArn: functionArn(),
+			Runtime:     functionRuntime(),
+			InvokePath:  invokePath,
+		})
+	})
+}
+
+func rootBehavior() string {
+	behavior := GetenvWithDefault(rootBehaviorEnvKey, rootBehaviorInvoke)
+	if behavior != rootBehaviorInvoke && behavior != rootBehaviorInfo {
+		log.Warnf("Invalid %s %q, using %s", rootBehaviorEnvKey, behavior, rootBehaviorInvoke)
+		return rootBehaviorInvoke
+	}
+	return behavior
+}
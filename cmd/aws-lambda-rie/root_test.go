@@ -0,0 +1,46 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnswerRootInfo(t *testing.T) {
+	invoked := 0
+	handler := answerRootInfo(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked++
+	}))
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	serve("GET", "/")
+	assert.Equal(t, 1, invoked, "GET / invokes the function by default")
+
+	t.Setenv(rootBehaviorEnvKey, rootBehaviorInfo)
+	w := serve("GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, invoked)
+	var info rootInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "ok", info.Status)
+	assert.Equal(t, functionArn(), info.FunctionArn)
+	assert.Equal(t, invokePath, info.InvokePath)
+
+	serve("POST", "/")
+	serve("GET", "/orders")
+	assert.Equal(t, 3, invoked, "only a bare GET / is answered")
+
+	t.Setenv(rootBehaviorEnvKey, "browse")
+	serve("GET", "/")
+	assert.Equal(t, 4, invoked, "invalid behaviors fall back to invoke")
+}
@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	runtimeVersionEnvKey    = "AWS_LAMBDA_RIE_RUNTIME_VERSION"
+	runtimeVersionArnEnvKey = "AWS_LAMBDA_RIE_RUNTIME_VERSION_ARN"
+	executionEnvPrefix      = "AWS_Lambda_"
+)
+
+// runtimeInfo is the runtime version Lambda reports in the INIT_START line, e.g. python:3.12, taken from
+// AWS_LAMBDA_RIE_RUNTIME_VERSION or detected from the AWS_EXECUTION_ENV of the function, which the AWS
+// base images set. The ARN defaults to a stable one derived from the version.
+func runtimeInfo(executionEnv string) interop.RuntimeInfo {
+	version := GetenvWithDefault(runtimeVersionEnvKey, "")
+	if version == "" {
+		version = detectRuntimeVersion(strings.TrimPrefix(executionEnv, executionEnvPrefix))
+	}
+
+	arn := GetenvWithDefault(runtimeVersionArnEnvKey, "")
+	if arn == "" {
+		digest := sha256.Sum256([]byte(version))
+		arn = fmt.Sprintf("arn:aws:lambda:%s::runtime:%s", functionRegion(), hex.EncodeToString(digest[:]))
+	}
+	return interop.RuntimeInfo{ImageJSON: "{}", Arn: arn, Version: version}
+}
+
+// detectRuntimeVersion splits a runtime identifier the way Lambda names runtime versions:
+// nodejs18.x gives nodejs:18, python3.12 gives python:3.12 and provided.al2 gives provided:al2
+func detectRuntimeVersion(runtime string) string {
+	runtime = strings.TrimSuffix(runtime, ".x")
+	if i := strings.IndexAny(runtime, "0123456789."); i > 0 {
+		return runtime[:i] + ":" + strings.TrimPrefix(runtime[i:], ".")
+	}
+	return runtime
+}
+
+func printInitStart(runtime interop.RuntimeInfo, functionName string, functionVersion string) {
+	if platformLogFormat == logFormatJSON {
+		printPlatformEvent("platform.initStart", interop.InitStartData{
+			InitializationType: "on-demand",
+			Phase:              interop.InitPhase("init"),
+			RuntimeVersion:     runtime.Version,
+			RuntimeVersionArn:  runtime.Arn,
+			FunctionName:       functionName,
+			FunctionVersion:    functionVersion,
+		})
+		return
+	}
+	fmt.Fprintf(platformLog, "INIT_START Runtime Version: %s\tRuntime Version ARN: %s\n", runtime.Version, runtime.Arn)
+}
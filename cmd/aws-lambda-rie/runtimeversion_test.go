@@ -0,0 +1,46 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRuntimeVersion(t *testing.T) {
+	for runtime, version := range map[string]string{
+		"nodejs18.x":   "nodejs:18",
+		"python3.12":   "python:3.12",
+		"java21":       "java:21",
+		"provided.al2": "provided:al2",
+		"provided":     "provided",
+	} {
+		assert.Equal(t, version, detectRuntimeVersion(runtime), runtime)
+	}
+}
+
+func TestInitStartReportsRuntimeVersion(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+	t.Setenv("AWS_EXECUTION_ENV", "AWS_Lambda_python3.12")
+
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	invoke(t, sandbox, newInvokeRequest("{}"))
+
+	detected := sandbox.lastInit.RuntimeInfo
+	assert.Equal(t, "python:3.12", detected.Version)
+	assert.Regexp(t, `^arn:aws:lambda:us-east-1::runtime:[0-9a-f]{64}$`, detected.Arn)
+	assert.Contains(t, platform.String(), "INIT_START Runtime Version: python:3.12\tRuntime Version ARN: "+detected.Arn+"\n")
+	assert.Equal(t, detected.Arn, runtimeInfo("AWS_Lambda_python3.12").Arn, "the ARN is stable")
+
+	t.Setenv(runtimeVersionEnvKey, "python:3.12.v18")
+	t.Setenv(runtimeVersionArnEnvKey, "arn:aws:lambda:eu-west-1::runtime:abc")
+	invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, "python:3.12.v18", sandbox.lastInit.RuntimeInfo.Version)
+	assert.Equal(t, "arn:aws:lambda:eu-west-1::runtime:abc", sandbox.lastInit.RuntimeInfo.Arn)
+}
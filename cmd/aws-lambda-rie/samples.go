@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+// sampleEvents are canonical events of the common triggers, named after their file
+//
+//go:embed samples/*.json
+var sampleEvents embed.FS
+
+func sampleEvent(name string) ([]byte, bool) {
+	body, err := sampleEvents.ReadFile(path.Join("samples", name+".json"))
+	return body, err == nil
+}
+
+func sampleEventNames() []string {
+	entries, _ := sampleEvents.ReadDir("samples")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SampleHandler invokes the function with the named sample event, through the regular invoke path
+func SampleHandler(w http.ResponseWriter, r *http.Request, router http.Handler) {
+	name := chi.URLParam(r, "name")
+	body, found := sampleEvent(name)
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "ResourceNotFound", "No sample event "+name+", available samples: "+strings.Join(sampleEventNames(), ", "))
+		return
+	}
+
+	invoke, err := http.NewRequest(http.MethodPost, invokePath, bytes.NewReader(body))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "SampleInvokeFailed", err.Error())
+		return
+	}
+	invoke.Header.Set("Content-Type", "application/json")
+	invoke.RemoteAddr = r.RemoteAddr
+
+	log.Infof("Invoking with sample event %s", name)
+	router.ServeHTTP(w, invoke)
+}
@@ -0,0 +1,48 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleEventsAreValidJSON(t *testing.T) {
+	names := sampleEventNames()
+	assert.Equal(t, []string{"alb", "apigw-v1", "apigw-v2", "eventbridge", "s3", "sns", "sqs"}, names)
+
+	for _, name := range names {
+		body, found := sampleEvent(name)
+		assert.True(t, found)
+		assert.True(t, json.Valid(body), "sample %s is not valid JSON", name)
+	}
+}
+
+func TestSampleHandlerInvokesWithSampleEvent(t *testing.T) {
+	var invoked []byte
+	router := chi.NewRouter()
+	router.Post(invokePath, func(w http.ResponseWriter, r *http.Request) {
+		invoked, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`"ok"`))
+	})
+	router.Post("/_rie/sample/{name}", func(w http.ResponseWriter, r *http.Request) { SampleHandler(w, r, router) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/_rie/sample/sqs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"ok"`, w.Body.String())
+	expected, _ := sampleEvent("sqs")
+	assert.Equal(t, expected, invoked)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/_rie/sample/kafka", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "apigw-v2")
+}
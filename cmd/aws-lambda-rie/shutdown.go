@@ -0,0 +1,59 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/metering"
+)
+
+type sandboxShutdown interface {
+	Shutdown(shutdown *interop.Shutdown) *statejson.InternalStateDescription
+}
+
+// gracefulShutdown stops accepting requests on SIGINT and SIGTERM, gives the invokes in flight
+// the grace period to complete and then shuts the sandbox down so that the runtime exits cleanly
+type gracefulShutdown struct {
+	mutex   sync.Mutex
+	server  *http.Server
+	sandbox sandboxShutdown
+	grace   time.Duration
+}
+
+func newGracefulShutdown(sandbox sandboxShutdown, grace time.Duration) *gracefulShutdown {
+	return &gracefulShutdown{sandbox: sandbox, grace: grace}
+}
+
+// serve registers the server to drain, startHTTPServer calls it before accepting requests
+func (g *gracefulShutdown) serve(server *http.Server) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.server = server
+}
+
+// drain is registered with SandboxBuilder.AddDrainFunc, the shutdown functions that run after it exit the process
+func (g *gracefulShutdown) drain() {
+	g.mutex.Lock()
+	server := g.server
+	g.mutex.Unlock()
+
+	if server != nil {
+		log.Infof("Waiting up to %s for the invokes in flight to complete", g.grace)
+		ctx, cancel := context.WithTimeout(context.Background(), g.grace)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.WithError(err).Warn("Invokes still in flight after the grace period, closing their connections")
+			server.Close()
+		}
+	}
+
+	g.sandbox.Shutdown(&interop.Shutdown{DeadlineNs: metering.Monotime() + g.grace.Nanoseconds()})
+}
@@ -0,0 +1,93 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+)
+
+type recordingShutdown struct {
+	calls int
+}
+
+func (s *recordingShutdown) Shutdown(shutdown *interop.Shutdown) *statejson.InternalStateDescription {
+	s.calls++
+	return &statejson.InternalStateDescription{}
+}
+
+func TestGracefulShutdownDrainsInvokes(t *testing.T) {
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	started := make(chan struct{})
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`"done"`))
+		return nil
+	}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		InvokeHandler(w, r, sandbox, NewSimpleBootstrap([]string{}, ""))
+	})}
+	sandboxShutdown := &recordingShutdown{}
+	shutdown := newGracefulShutdown(sandboxShutdown, 5*time.Second)
+	shutdown.serve(server)
+	go server.Serve(listener)
+
+	url := "http://" + listener.Addr().String() + invokePath
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Post(url, "application/json", nil)
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	shutdown.drain()
+
+	assert.Equal(t, `"done"`, <-responses)
+	assert.Equal(t, 1, sandboxShutdown.calls)
+	_, err = http.Post(url, "application/json", nil)
+	assert.Error(t, err, "no new invokes are accepted after the drain")
+}
+
+func TestGracefulShutdownClosesAfterGracePeriod(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	release := make(chan struct{})
+	defer close(release)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release })}
+	sandboxShutdown := &recordingShutdown{}
+	shutdown := newGracefulShutdown(sandboxShutdown, 50*time.Millisecond)
+	shutdown.serve(server)
+	go server.Serve(listener)
+
+	failed := make(chan error, 1)
+	go func() {
+		_, err := http.Get("http://" + listener.Addr().String())
+		failed <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	shutdown.drain()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Error(t, <-failed)
+	assert.Equal(t, 1, sandboxShutdown.calls)
+}
@@ -0,0 +1,53 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	responseSinkEnvKey     = "AWS_LAMBDA_RIE_RESPONSE_SINK"
+	responseSinkModeEnvKey = "AWS_LAMBDA_RIE_RESPONSE_SINK_MODE"
+	responseSinkOverwrite  = "overwrite"
+	responseSinkAppend     = "append"
+)
+
+var responseSinkMutex sync.Mutex
+
+// sinkResponse writes the response body to the AWS_LAMBDA_RIE_RESPONSE_SINK file, replacing the
+// previous response or, in append mode, after it on a new line. The HTTP response is sent either way.
+func sinkResponse(body []byte) {
+	path := GetenvWithDefault(responseSinkEnvKey, "")
+	if path == "" {
+		return
+	}
+	body = redactPayload(body)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	mode := GetenvWithDefault(responseSinkModeEnvKey, responseSinkOverwrite)
+	switch mode {
+	case responseSinkAppend:
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		body = append(body[:len(body):len(body)], '\n')
+	case responseSinkOverwrite:
+	default:
+		log.Warnf("Invalid %s %q, using %s", responseSinkModeEnvKey, mode, responseSinkOverwrite)
+	}
+
+	responseSinkMutex.Lock()
+	defer responseSinkMutex.Unlock()
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		log.Errorf("Failed to open response sink: %s", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(body); err != nil {
+		log.Errorf("Failed to write response sink: %s", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	t.Setenv(responseSinkEnvKey, path)
+	sunk := func() string {
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	w := invoke(t, &mockSandbox{invoke: respondWith(`"first"`)}, newInvokeRequest("{}"))
+	assert.Equal(t, `"first"`, w.Body.String(), "the client still gets the response")
+	assert.Equal(t, `"first"`, sunk())
+
+	invoke(t, &mockSandbox{invoke: respondWith(`"second"`)}, newInvokeRequest("{}"))
+	assert.Equal(t, `"second"`, sunk(), "the latest response replaces the previous one by default")
+
+	t.Setenv(responseSinkModeEnvKey, responseSinkAppend)
+	invoke(t, &mockSandbox{invoke: respondWithFunctionError(`{"errorType":"Exception"}`)}, newInvokeRequest("{}"))
+	assert.Equal(t, `"second"{"errorType":"Exception"}`+"\n", sunk())
+}
@@ -0,0 +1,122 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	checkpointResetReason    = "checkpoint"
+	checkpointResetTimeoutMs = 2000
+	restoreTimeoutParam      = "timeoutMs"
+	// Lambda gives the runtime hooks of a restore 10 seconds
+	defaultRestoreHookTimeoutMs = 10000
+	checkpointFailedType        = "CheckpointFailed"
+	restoreFailedType           = "RestoreFailed"
+)
+
+// snapStartServer is the part of rapidcore.Server the SnapStart lifecycle is driven through
+type snapStartServer interface {
+	AwaitInitialized() error
+	Reset(reason string, timeoutMs int64) (*statejson.ResetDescription, error)
+	Restore(restore *interop.Restore) (interop.RestoreResult, error)
+	InternalState() (*statejson.InternalStateDescription, error)
+}
+
+type snapStartResponse struct {
+	RestoreMs     *int64                              `json:"restoreMs,omitempty"`
+	InternalState *statejson.InternalStateDescription `json:"internalState,omitempty"`
+}
+
+// CheckpointHandler runs a fresh init of the function and waits for it to complete, which is when Lambda would take
+// the snapshot of a SnapStart function: the runtime ran its beforeCheckpoint hooks and waits in /restore/next.
+// A function that was initialized already is reset first, so that every checkpoint runs the hooks again.
+func CheckpointHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox, server snapStartServer, bs interop.Bootstrap) {
+	timeoutDuration, err := functionTimeout()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, checkpointFailedType, err.Error())
+		return
+	}
+
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
+	if initDone {
+		log.Info("Resetting the sandbox for a checkpoint")
+		if _, err := server.Reset(checkpointResetReason, checkpointResetTimeoutMs); err != nil {
+			log.Warnf("Reset before checkpoint failed: %s", err)
+		}
+		initDone = false
+	}
+
+	InitHandler(sandbox, GetenvWithDefault("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST"), timeoutDuration.Milliseconds(), bs)
+	if err := server.AwaitInitialized(); err != nil {
+		log.Errorf("Init for the checkpoint failed: %s", err)
+		// the failure is consumed, the next invoke must not take the sandbox for an initialized one
+		if _, err := server.Reset(checkpointResetReason, checkpointResetTimeoutMs); err != nil {
+			log.Warnf("Reset after failed checkpoint failed: %s", err)
+		}
+		writeJSONError(w, http.StatusBadGateway, checkpointFailedType, fmt.Sprintf("init failed: %s", err))
+		return
+	}
+	initDone = true
+	// like in Lambda, the invokes of a restored function do not report the init that preceded the snapshot
+	initDurations.take()
+
+	resp := snapStartResponse{}
+	resp.InternalState, _ = server.InternalState()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RestoreHandler restores the function checkpointed by CheckpointHandler: rapid releases the runtime from
+// /restore/next with the credentials of the emulator, and waits for its afterRestore hooks to complete
+func RestoreHandler(w http.ResponseWriter, r *http.Request, server snapStartServer) {
+	hookTimeoutMs := int64(defaultRestoreHookTimeoutMs)
+	if configured := r.URL.Query().Get(restoreTimeoutParam); configured != "" {
+		timeoutMs, err := strconv.ParseInt(configured, 10, 64)
+		if err != nil || timeoutMs <= 0 {
+			writeJSONError(w, http.StatusBadRequest, ClientInvalidRequest.String(), fmt.Sprintf("invalid %s %q", restoreTimeoutParam, configured))
+			return
+		}
+		hookTimeoutMs = timeoutMs
+	}
+
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
+	if !initDone {
+		writeJSONError(w, http.StatusConflict, restoreFailedType, "nothing to restore, POST "+adminPathPrefix+"/checkpoint first")
+		return
+	}
+
+	result, err := server.Restore(&interop.Restore{
+		AwsKey:               os.Getenv("AWS_ACCESS_KEY_ID"),
+		AwsSecret:            os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AwsSession:           os.Getenv("AWS_SESSION_TOKEN"),
+		RestoreHookTimeoutMs: hookTimeoutMs,
+	})
+	if errors.Is(err, interop.ErrRestoreUpdateCredentials) {
+		writeJSONError(w, http.StatusConflict, restoreFailedType, "restores need the emulator to run with --enable-init-caching")
+		return
+	}
+	if err != nil {
+		log.Errorf("Restore failed: %s", err)
+		writeJSONError(w, http.StatusBadGateway, restoreFailedType, err.Error())
+		return
+	}
+	fmt.Fprintf(platformLog, "RESTORE_REPORT Restore Duration: %d ms\n", result.RestoreMs)
+
+	resp := snapStartResponse{RestoreMs: &result.RestoreMs}
+	resp.InternalState, _ = server.InternalState()
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,101 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+)
+
+type mockSnapStartServer struct {
+	initErr     error
+	restoreErr  error
+	resets      int
+	lastRestore *interop.Restore
+}
+
+func (s *mockSnapStartServer) AwaitInitialized() error { return s.initErr }
+
+func (s *mockSnapStartServer) Reset(reason string, timeoutMs int64) (*statejson.ResetDescription, error) {
+	s.resets++
+	return &statejson.ResetDescription{}, nil
+}
+
+func (s *mockSnapStartServer) Restore(restore *interop.Restore) (interop.RestoreResult, error) {
+	s.lastRestore = restore
+	return interop.RestoreResult{RestoreMs: 12}, s.restoreErr
+}
+
+func (s *mockSnapStartServer) InternalState() (*statejson.InternalStateDescription, error) {
+	return &statejson.InternalStateDescription{FirstFatalError: "none"}, nil
+}
+
+func TestCheckpointAndRestore(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+
+	sandbox := &mockSandbox{}
+	server := &mockSnapStartServer{}
+	checkpoint := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		CheckpointHandler(w, httptest.NewRequest("POST", adminPathPrefix+"/checkpoint", nil), sandbox, server, NewSimpleBootstrap([]string{}, ""))
+		return w
+	}
+	restore := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		RestoreHandler(w, httptest.NewRequest("POST", target, nil), server)
+		return w
+	}
+
+	w := restore(adminPathPrefix + "/restore")
+	assert.Equal(t, http.StatusConflict, w.Code, "nothing was checkpointed")
+	assert.Nil(t, server.lastRestore)
+
+	w = checkpoint()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"internalState": {"runtime": null, "extensions": null, "firstFatalError": "none"}}`, w.Body.String())
+	assert.Equal(t, 1, sandbox.initCalls)
+	assert.Equal(t, 0, server.resets)
+	assert.True(t, initDone)
+
+	w = restore(adminPathPrefix + "/restore?timeoutMs=500")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"restoreMs":12`)
+	assert.Equal(t, "key", server.lastRestore.AwsKey)
+	assert.Equal(t, int64(500), server.lastRestore.RestoreHookTimeoutMs)
+	assert.Contains(t, platform.String(), "RESTORE_REPORT Restore Duration: 12 ms\n")
+
+	restore(adminPathPrefix + "/restore")
+	assert.Equal(t, int64(defaultRestoreHookTimeoutMs), server.lastRestore.RestoreHookTimeoutMs)
+	assert.Equal(t, http.StatusBadRequest, restore(adminPathPrefix+"/restore?timeoutMs=soon").Code)
+
+	checkpoint()
+	assert.Equal(t, 2, sandbox.initCalls)
+	assert.Equal(t, 1, server.resets, "a checkpoint of an initialized function runs the init again")
+
+	server.restoreErr = interop.ErrRestoreUpdateCredentials
+	w = restore(adminPathPrefix + "/restore")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "--enable-init-caching")
+
+	server.restoreErr = interop.ErrRestoreHookTimeout
+	assert.Equal(t, http.StatusBadGateway, restore(adminPathPrefix+"/restore").Code)
+
+	server.initErr = interop.ErrRestoreHookTimeout
+	w = checkpoint()
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), checkpointFailedType)
+	assert.False(t, initDone, "the next invoke initializes the function again")
+}
@@ -0,0 +1,114 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+)
+
+const (
+	sandboxStatusIdle         = "idle"
+	sandboxStatusBusy         = "busy"
+	sandboxStatusInitializing = "initializing"
+	sandboxStatusFailed       = "failed"
+)
+
+type sandboxStats struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Invokes  int        `json:"invokes"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+}
+
+// trackedSandbox records the status and usage of a sandbox for the state endpoint.
+// The emulator runs a single sandbox today, the state reports a list so that a pool can add its members.
+type trackedSandbox struct {
+	Sandbox
+	mutex sync.Mutex
+	stats sandboxStats
+}
+
+func newTrackedSandbox(id string, sandbox Sandbox) *trackedSandbox {
+	return &trackedSandbox{Sandbox: sandbox, stats: sandboxStats{ID: id, Status: sandboxStatusIdle}}
+}
+
+func (s *trackedSandbox) Init(i *interop.Init, invokeTimeoutMs int64) {
+	s.setStatus(sandboxStatusInitializing)
+	s.Sandbox.Init(i, invokeTimeoutMs)
+}
+
+func (s *trackedSandbox) Invoke(w http.ResponseWriter, i *interop.Invoke) error {
+	s.setStatus(sandboxStatusBusy)
+	err := s.Sandbox.Invoke(w, i)
+	if err == rapidcore.ErrAlreadyReserved {
+		// rejected, the invoke that holds the sandbox updates the stats
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now().UTC()
+	s.stats.Invokes++
+	s.stats.LastUsed = &now
+	switch err {
+	case rapidcore.ErrInitDoneFailed, rapidcore.ErrInvokeDoneFailed, rapidcore.ErrInvokeTimeout:
+		s.stats.Status = sandboxStatusFailed
+	default:
+		s.stats.Status = sandboxStatusIdle
+	}
+	return err
+}
+
+func (s *trackedSandbox) setStatus(status string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stats.Status = status
+}
+
+// whileIdle runs fn unless the sandbox is serving an invoke, and tells whether it did. An invoke that starts in the
+// meantime waits for fn to return.
+func (s *trackedSandbox) whileIdle(fn func()) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stats.Status == sandboxStatusBusy {
+		return false
+	}
+	fn()
+	return true
+}
+
+func (s *trackedSandbox) snapshot() sandboxStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stats
+}
+
+type stateResponse struct {
+	Sandboxes     []sandboxStats                      `json:"sandboxes"`
+	TimeoutMs     int64                               `json:"timeoutMs,omitempty"` // after the AWS_LAMBDA_RIE_MAX_TIMEOUT_MS ceiling
+	AsyncQueue    asyncQueueStats                     `json:"asyncQueue"`
+	InternalState *statejson.InternalStateDescription `json:"internalState,omitempty"`
+}
+
+// StateHandler reports the emulator's sandboxes along with rapid's internal state
+func StateHandler(w http.ResponseWriter, r *http.Request, sandboxes []*trackedSandbox, internalState func() (*statejson.InternalStateDescription, error)) {
+	resp := stateResponse{Sandboxes: []sandboxStats{}, AsyncQueue: eventInvokes.stats()}
+	for _, sandbox := range sandboxes {
+		resp.Sandboxes = append(resp.Sandboxes, sandbox.snapshot())
+	}
+
+	if timeout, err := functionTimeout(); err == nil {
+		resp.TimeoutMs = timeout.Milliseconds()
+	}
+	if state, err := internalState(); err == nil {
+		resp.InternalState = state
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,47 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapidcore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateReportsSandboxUsage(t *testing.T) {
+	sandbox := newTrackedSandbox("0", &mockSandbox{invoke: respondWith(`"ok"`)})
+	noInternalState := func() (*statejson.InternalStateDescription, error) { return nil, errors.New("not started") }
+
+	state := func() stateResponse {
+		w := httptest.NewRecorder()
+		StateHandler(w, httptest.NewRequest("GET", "/_rie/state", nil), []*trackedSandbox{sandbox}, noInternalState)
+		var resp stateResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	initial := state()
+	assert.Equal(t, sandboxStatusIdle, initial.Sandboxes[0].Status)
+	assert.Nil(t, initial.Sandboxes[0].LastUsed)
+	assert.Equal(t, eventInvokes.stats(), initial.AsyncQueue)
+
+	invoke(t, sandbox, newInvokeRequest("{}"))
+	invoke(t, sandbox, newInvokeRequest("{}"))
+	used := state()
+	assert.Equal(t, 2, used.Sandboxes[0].Invokes)
+	assert.Equal(t, sandboxStatusIdle, used.Sandboxes[0].Status)
+	assert.NotNil(t, used.Sandboxes[0].LastUsed)
+
+	sandbox.Sandbox = &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error { return rapidcore.ErrInvokeDoneFailed }}
+	invoke(t, sandbox, newInvokeRequest("{}"))
+	assert.Equal(t, sandboxStatusFailed, state().Sandboxes[0].Status)
+}
@@ -0,0 +1,75 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	invokeTagHeaderPrefix = "X-Amz-Rie-Tag-"
+
+	maxInvokeTags          = 10
+	maxInvokeTagNameBytes  = 64
+	maxInvokeTagValueBytes = 256
+)
+
+type invokeTag struct {
+	name  string
+	value string
+}
+
+// extractInvokeTags moves the X-Amz-Rie-Tag-* headers of the request to its context, so that
+// they are reported with the invoke without reaching the function
+func extractInvokeTags(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tags []invokeTag
+		for k, vs := range r.Header {
+			if !strings.HasPrefix(k, invokeTagHeaderPrefix) {
+				continue
+			}
+			r.Header.Del(k)
+
+			name := strings.ToLower(strings.TrimPrefix(k, invokeTagHeaderPrefix))
+			value := strings.Join(vs, ",")
+			switch {
+			case name == "":
+				continue
+			case len(name) > maxInvokeTagNameBytes || len(value) > maxInvokeTagValueBytes:
+				log.Warnf("Dropped invoke tag %s, tag names are limited to %d bytes and values to %d bytes", name, maxInvokeTagNameBytes, maxInvokeTagValueBytes)
+				continue
+			}
+			tags = append(tags, invokeTag{name: name, value: value})
+		}
+
+		sort.Slice(tags, func(i, j int) bool { return tags[i].name < tags[j].name })
+		if len(tags) > maxInvokeTags {
+			log.Warnf("Dropped %d invoke tags, at most %d are reported", len(tags)-maxInvokeTags, maxInvokeTags)
+			tags = tags[:maxInvokeTags]
+		}
+		if len(tags) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), invokeTagsKey, tags))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reportedTags formats the tags of the request as a REPORT field, e.g. "Tags: case=login, suite=smoke\t"
+func reportedTags(r *http.Request) string {
+	tags, _ := r.Context().Value(invokeTagsKey).([]invokeTag)
+	if len(tags) == 0 {
+		return ""
+	}
+
+	fields := make([]string, len(tags))
+	for i, tag := range tags {
+		fields[i] = tag.name + "=" + tag.value
+	}
+	return "Tags: " + strings.Join(fields, ", ") + "\t"
+}
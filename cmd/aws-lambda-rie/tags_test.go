@@ -0,0 +1,58 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvokeTagsAreReportedAndNotForwarded(t *testing.T) {
+	var platform bytes.Buffer
+	platformLog = &platform
+	t.Cleanup(func() { platformLog = os.Stdout })
+
+	var forwarded http.Header
+	sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+	handler := extractInvokeTags(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+		invoke(t, sandbox, r)
+	}))
+
+	req := newInvokeRequest("{}")
+	req.Header.Set("X-Amz-Rie-Tag-Test-Case", "login")
+	req.Header.Set("X-Amz-Rie-Tag-Suite", "100% smoke")
+	req.Header.Set("X-Custom", "kept")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "kept", forwarded.Get("X-Custom"))
+	assert.Empty(t, forwarded.Get("X-Amz-Rie-Tag-Test-Case"))
+	assert.Contains(t, platform.String(), "\tTags: suite=100% smoke, test-case=login\t\n")
+}
+
+func TestInvokeTagsAreBounded(t *testing.T) {
+	var tags string
+	handler := extractInvokeTags(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { tags = reportedTags(r) }))
+
+	req := newInvokeRequest("{}")
+	for i := 0; i < maxInvokeTags+2; i++ {
+		req.Header.Set(fmt.Sprintf("X-Amz-Rie-Tag-T%02d", i), "v")
+	}
+	req.Header.Set("X-Amz-Rie-Tag-Large", strings.Repeat("v", maxInvokeTagValueBytes+1))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, maxInvokeTags, strings.Count(tags, "=v"))
+	assert.NotContains(t, tags, "large")
+	assert.NotContains(t, tags, "t10")
+
+	handler.ServeHTTP(httptest.NewRecorder(), newInvokeRequest("{}"))
+	assert.Empty(t, tags)
+}
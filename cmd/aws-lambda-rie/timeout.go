@@ -0,0 +1,93 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	functionTimeoutEnvKey = "AWS_LAMBDA_FUNCTION_TIMEOUT"
+	maxTimeoutEnvKey      = "AWS_LAMBDA_RIE_MAX_TIMEOUT_MS"
+	timeoutHeader         = "X-Rie-Timeout-Seconds"
+	initTimeoutEnvKey     = "AWS_LAMBDA_INIT_TIMEOUT"
+	defaultTimeoutSeconds = "300"
+	// the init phase gets 10 seconds in Lambda, whatever the function timeout
+	defaultInitTimeout = 10 * time.Second
+	// the largest timeout Lambda accepts
+	defaultMaxTimeout = 900 * time.Second
+)
+
+// functionTimeout is the invoke timeout configured with AWS_LAMBDA_FUNCTION_TIMEOUT,
+// clamped to the AWS_LAMBDA_RIE_MAX_TIMEOUT_MS ceiling
+func functionTimeout() (time.Duration, error) {
+	configured := GetenvWithDefault(functionTimeoutEnvKey, defaultTimeoutSeconds)
+	seconds, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", functionTimeoutEnvKey, configured, err)
+	}
+	return clampTimeout(time.Duration(seconds)*time.Second, functionTimeoutEnvKey), nil
+}
+
+// initTimeout is the time the init phase gets, in seconds, configured with AWS_LAMBDA_INIT_TIMEOUT
+func initTimeout() time.Duration {
+	configured := GetenvWithDefault(initTimeoutEnvKey, "")
+	if configured == "" {
+		return defaultInitTimeout
+	}
+
+	seconds, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || seconds <= 0 {
+		log.Warnf("Invalid %s %q, using %s", initTimeoutEnvKey, configured, defaultInitTimeout)
+		return defaultInitTimeout
+	}
+	return clampTimeout(time.Duration(seconds)*time.Second, initTimeoutEnvKey)
+}
+
+// clampTimeout caps a timeout taken from source at the ceiling, so that a runaway
+// configuration cannot leave an invoke hanging for good
+func clampTimeout(timeout time.Duration, source string) time.Duration {
+	ceiling := maxTimeout()
+	if timeout > ceiling {
+		log.Warnf("%s of %s exceeds the %s ceiling, using %s", source, timeout, maxTimeoutEnvKey, ceiling)
+		return ceiling
+	}
+	return timeout
+}
+
+func maxTimeout() time.Duration {
+	configured := GetenvWithDefault(maxTimeoutEnvKey, "")
+	if configured == "" {
+		return defaultMaxTimeout
+	}
+
+	ms, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil || ms <= 0 {
+		log.Warnf("Invalid %s %q, using %s", maxTimeoutEnvKey, configured, defaultMaxTimeout)
+		return defaultMaxTimeout
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// requestTimeout is the timeout of a single invoke set with the X-Rie-Timeout-Seconds header,
+// e.g. 0.5, and false when the header is not set or invalid
+func requestTimeout(r *http.Request) (time.Duration, bool) {
+	configured := r.Header.Get(timeoutHeader)
+	if configured == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseFloat(configured, 64)
+	if err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+		log.Warnf("Invalid %s %q, using the function timeout", timeoutHeader, configured)
+		return 0, false
+	}
+	return clampTimeout(time.Duration(seconds*float64(time.Second)), timeoutHeader), true
+}
@@ -0,0 +1,114 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.amzn.com/lambda/core/statejson"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/metering"
+	"go.amzn.com/lambda/rapidcore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctionTimeoutIsClampedToCeiling(t *testing.T) {
+	timeout := func() time.Duration {
+		d, err := functionTimeout()
+		assert.NoError(t, err)
+		return d
+	}
+
+	assert.Equal(t, 300*time.Second, timeout())
+
+	t.Setenv(functionTimeoutEnvKey, "100000")
+	assert.Equal(t, defaultMaxTimeout, timeout())
+
+	t.Setenv(maxTimeoutEnvKey, "1500")
+	assert.Equal(t, 1500*time.Millisecond, timeout())
+
+	t.Setenv(functionTimeoutEnvKey, "1")
+	assert.Equal(t, time.Second, timeout(), "timeouts below the ceiling are kept")
+
+	t.Setenv(maxTimeoutEnvKey, "never")
+	t.Setenv(functionTimeoutEnvKey, "1000")
+	assert.Equal(t, defaultMaxTimeout, timeout())
+
+	t.Setenv(functionTimeoutEnvKey, "soon")
+	_, err := functionTimeout()
+	assert.Error(t, err)
+}
+
+func TestStateReportsEffectiveTimeout(t *testing.T) {
+	t.Setenv(functionTimeoutEnvKey, "5")
+	t.Setenv(maxTimeoutEnvKey, "2000")
+
+	noInternalState := func() (*statejson.InternalStateDescription, error) { return nil, errors.New("not started") }
+
+	w := httptest.NewRecorder()
+	StateHandler(w, httptest.NewRequest("GET", "/_rie/state", nil), nil, noInternalState)
+	var resp stateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(2000), resp.TimeoutMs)
+}
+
+func TestInvokeHonorsTimeoutHeader(t *testing.T) {
+	t.Setenv(functionTimeoutEnvKey, "1")
+
+	var deadlineNs string
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		deadlineNs = i.DeadlineNs
+		return rapidcore.ErrInvokeTimeout
+	}}
+
+	req := newInvokeRequest("{}")
+	req.Header.Set(timeoutHeader, "2")
+	overridden := invoke(t, sandbox, req)
+	assert.JSONEq(t, `{"errorType": "Sandbox.Timedout", "errorMessage": "Task timed out after 2.00 seconds"}`, overridden.Body.String())
+	deadline, err := strconv.ParseInt(deadlineNs, 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, metering.Monotime()+(2*time.Second).Nanoseconds(), deadline, float64(time.Second))
+
+	for _, invalid := range []string{"soon", "0", "-1"} {
+		req = newInvokeRequest("{}")
+		req.Header.Set(timeoutHeader, invalid)
+		fallback := invoke(t, sandbox, req)
+		assert.JSONEq(t, `{"errorType": "Sandbox.Timedout", "errorMessage": "Task timed out after 1.00 seconds"}`, fallback.Body.String(), invalid)
+		assert.Empty(t, deadlineNs, "the sandbox applies the function timeout")
+	}
+
+	t.Setenv(maxTimeoutEnvKey, "1500")
+	req = newInvokeRequest("{}")
+	req.Header.Set(timeoutHeader, "60")
+	clamped := invoke(t, sandbox, req)
+	assert.JSONEq(t, `{"errorType": "Sandbox.Timedout", "errorMessage": "Task timed out after 1.50 seconds"}`, clamped.Body.String())
+}
+
+func TestInitGetsItsOwnTimeout(t *testing.T) {
+	initTimeoutMsOf := func() int64 {
+		sandbox := &mockSandbox{invoke: respondWith(`"ok"`)}
+		invoke(t, sandbox, newInvokeRequest("{}"))
+		return sandbox.lastInit.InitTimeoutMs
+	}
+
+	t.Setenv(functionTimeoutEnvKey, "60")
+	assert.Equal(t, int64(10000), initTimeoutMsOf(), "init gets 10 seconds whatever the function timeout")
+
+	t.Setenv(initTimeoutEnvKey, "3")
+	assert.Equal(t, int64(3000), initTimeoutMsOf())
+
+	t.Setenv(initTimeoutEnvKey, "never")
+	assert.Equal(t, int64(10000), initTimeoutMsOf())
+
+	t.Setenv(maxTimeoutEnvKey, "1500")
+	t.Setenv(initTimeoutEnvKey, "20")
+	assert.Equal(t, int64(1500), initTimeoutMsOf())
+}
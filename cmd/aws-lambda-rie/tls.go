@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+)
+
+// selfSignedValidity is how long the certificate generated with --tls-self-signed is valid
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// newTLSConfig returns the TLS configuration of the invoke endpoint, nil when it serves plain HTTP
+func newTLSConfig(opts options) (*tls.Config, error) {
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return nil, errors.New("--tls-cert and --tls-key must be set together")
+	}
+	if opts.TLSSelfSigned && opts.TLSCert != "" {
+		return nil, errors.New("--tls-self-signed cannot be combined with --tls-cert and --tls-key")
+	}
+
+	var cert tls.Certificate
+	var err error
+	switch {
+	case opts.TLSCert != "":
+		cert, err = tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+	case opts.TLSSelfSigned:
+		cert, err = selfSignedCertificate()
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// HTTP/1.1 only, the recording of raw header names for AWS_LAMBDA_RIE_HEADER_CASE does not apply to HTTP/2
+	return &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"http/1.1"}}, nil
+}
+
+// selfSignedCertificate generates an in-memory certificate for localhost, 127.0.0.1 and ::1
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"AWS Lambda Runtime Interface Emulator"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	config, err := newTLSConfig(options{})
+	assert.NoError(t, err)
+	assert.Nil(t, config, "plain HTTP by default")
+
+	_, err = newTLSConfig(options{TLSCert: "cert.pem"})
+	assert.EqualError(t, err, "--tls-cert and --tls-key must be set together")
+	_, err = newTLSConfig(options{TLSKey: "key.pem"})
+	assert.EqualError(t, err, "--tls-cert and --tls-key must be set together")
+	_, err = newTLSConfig(options{TLSCert: "cert.pem", TLSKey: "key.pem", TLSSelfSigned: true})
+	assert.Error(t, err)
+
+	config, err = newTLSConfig(options{TLSSelfSigned: true})
+	require.NoError(t, err)
+	leaf := config.Certificates[0].Leaf
+	assert.NoError(t, leaf.VerifyHostname("localhost"))
+	assert.NoError(t, leaf.VerifyHostname("127.0.0.1"))
+
+	// the generated certificate written to files loads as a given one
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	key, err := x509.MarshalECPrivateKey(config.Certificates[0].PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600))
+	loaded, err := newTLSConfig(options{TLSCert: certFile, TLSKey: keyFile})
+	require.NoError(t, err)
+	assert.Equal(t, leaf.Raw, loaded.Certificates[0].Certificate[0])
+
+	_, err = newTLSConfig(options{TLSCert: filepath.Join(dir, "missing.pem"), TLSKey: keyFile})
+	assert.Error(t, err)
+}
+
+func TestTLSListenerPreservesHeaderCase(t *testing.T) {
+	config, err := newTLSConfig(options{TLSSelfSigned: true})
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var seen string
+	server := &http.Server{
+		Handler: preserveHeaderCase(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = eventHeaderName(r, "X-Mixed-Case", headerCaseCanonical)
+		})),
+		ConnContext: saveRecordingConn,
+	}
+	go server.Serve(recordingListener{tls.NewListener(listener, config)})
+	defer server.Close()
+
+	t.Setenv(headerCaseEnvKey, headerCasePreserve)
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nx-MiXeD-case: a\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "x-MiXeD-case", seen)
+}
@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/telemetry"
+)
+
+const (
+	tracePropagationEnvKey = "AWS_LAMBDA_RIE_TRACE_PROPAGATION"
+	traceIDHeader          = "X-Amzn-Trace-Id"
+	traceIDEnvKey          = "_X_AMZN_TRACE_ID"
+)
+
+func tracePropagationEnabled() bool {
+	return GetenvWithDefault(tracePropagationEnvKey, "false") == "true"
+}
+
+// newTraceID builds an X-Ray tracing header whose Parent is derived from the request ID,
+// so that downstream segments can be correlated with the invoke in the emulator logs
+// see https://docs.aws.amazon.com/xray/latest/devguide/xray-concepts.html#xray-concepts-tracingheader
+func newTraceID(requestID string) string {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return ""
+	}
+
+	parent := strings.ReplaceAll(requestID, "-", "")
+	if len(parent) > 16 {
+		parent = parent[:16]
+	}
+
+	root := fmt.Sprintf("1-%08x-%s", time.Now().Unix(), hex.EncodeToString(random))
+	return telemetry.BuildFullTraceID(root, parent, "0")
+}
+
+// invokeTraceID returns the tracing header sent by the client or, when trace propagation
+// is enabled, a new one correlated with the request ID
+func invokeTraceID(clientTraceID string, requestID string) string {
+	if clientTraceID != "" || !tracePropagationEnabled() {
+		return clientTraceID
+	}
+	return newTraceID(requestID)
+}
+
+// traceForwardingTracer forwards the invoke's tracing header to the runtime in the
+// Lambda-Runtime-Trace-Id header of /next, which runtimes expose as _X_AMZN_TRACE_ID
+type traceForwardingTracer struct {
+	*telemetry.NoOpTracer
+	mutex   sync.Mutex
+	traceID string
+}
+
+func newTraceForwardingTracer() *traceForwardingTracer {
+	return &traceForwardingTracer{NoOpTracer: telemetry.NewNoOpTracer()}
+}
+
+func (t *traceForwardingTracer) Configure(invoke *interop.Invoke) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.traceID = invoke.TraceID
+}
+
+func (t *traceForwardingTracer) BuildTracingHeader() func(context.Context) string {
+	return func(ctx context.Context) string {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		return t.traceID
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const uiEnvKey = "AWS_LAMBDA_RIE_UI"
+
+//go:embed ui/index.html
+var uiPage string
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiPage))
+
+func uiEnabled() bool {
+	return GetenvWithDefault(uiEnvKey, "false") == "true"
+}
+
+// UIHandler serves a page to invoke the function from a browser. It is not behind the admin
+// token since it only calls the invoke endpoints, which are not either.
+func UIHandler(w http.ResponseWriter, r *http.Request) {
+	if !uiEnabled() {
+		writeJSONError(w, http.StatusForbidden, "UIDisabled", "Set "+uiEnvKey+"=true to enable the web UI")
+		return
+	}
+
+	formats := make([]string, 0, len(eventFormats))
+	for name := range eventFormats {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := uiTemplate.Execute(w, struct {
+		Formats      []string
+		InvokePath   string
+		FormatHeader string
+	}{formats, invokePath, eventFormatHeader})
+	if err != nil {
+		log.Errorf("Failed to render the web UI: %s", err)
+	}
+}
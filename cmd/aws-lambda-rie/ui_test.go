@@ -0,0 +1,33 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUIDisabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	UIHandler(w, httptest.NewRequest("GET", "/_rie/ui", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUIListsEventFormats(t *testing.T) {
+	t.Setenv(uiEnvKey, "true")
+
+	w := httptest.NewRecorder()
+	UIHandler(w, httptest.NewRequest("GET", "/_rie/ui", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	for name := range eventFormats {
+		assert.Contains(t, w.Body.String(), `<option value="`+name+`">`)
+	}
+	assert.Contains(t, w.Body.String(), `data-invoke-path="`+invokePath+`"`)
+}
@@ -4,8 +4,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/http")
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/core/directinvoke"
+	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/rapi/model"
+)
 
 type ErrorType int
 
@@ -21,18 +29,91 @@ func (t ErrorType) String() string {
 	return fmt.Sprintf("Cannot stringify standalone.ErrorType.%d", int(t))
 }
 
+// ResponseWriterProxy buffers the response of the sandbox. When stream is set, a
+// streamed function response is instead forwarded to it as chunks are written.
 type ResponseWriterProxy struct {
 	Body       []byte
 	StatusCode int
+	Streamed   bool
+	header     http.Header
+	stream     http.ResponseWriter
+	// clientGone is set once a write to stream failed, the client disconnected
+	clientGone bool
+	// streamedBytes were forwarded to stream, truncated is set once more would exceed maxResponseBytes
+	streamedBytes int64
+	truncated     bool
+	// keepStreamed copies the streamed response to Body, for the response sink
+	keepStreamed bool
 }
 
 func (w *ResponseWriterProxy) Header() http.Header {
-	return http.Header{}
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
 }
 
 func (w *ResponseWriterProxy) Write(b []byte) (int, error) {
-	w.Body = b
-	return 0, nil
+	if !w.Streamed && w.stream != nil && w.isStreamingResponse() {
+		w.startStreaming()
+	}
+	if w.Streamed {
+		return w.writeStream(b)
+	}
+
+	w.Body = append(w.Body, b...)
+	return len(b), nil
+}
+
+// writeStream discards what the runtime streams once the client is gone, the function runs to
+// completion like in Lambda and the sandbox is released as usual rather than failing the response
+func (w *ResponseWriterProxy) writeStream(b []byte) (int, error) {
+	if w.clientGone || w.truncated {
+		return len(b), nil
+	}
+	if w.streamedBytes+int64(len(b)) > maxResponseBytes {
+		log.Warnf("Truncating the streamed response at %d bytes, the limit of --max-response-bytes is %d", w.streamedBytes, maxResponseBytes)
+		w.truncated = true
+		return len(b), nil
+	}
+	w.streamedBytes += int64(len(b))
+	if w.keepStreamed {
+		w.Body = append(w.Body, b...)
+	}
+	if _, err := w.stream.Write(b); err != nil {
+		log.Debugf("Discarding the rest of the streamed response, the client disconnected: %s", err)
+		w.clientGone = true
+	}
+	return len(b), nil
+}
+
+func (w *ResponseWriterProxy) isStreamingResponse() bool {
+	return strings.EqualFold(w.Header().Get(directinvoke.FunctionResponseModeHeader), string(interop.FunctionResponseModeStreaming))
+}
+
+// startStreaming sends the headers without a Content-Length, so that the
+// response is sent with Transfer-Encoding: chunked
+func (w *ResponseWriterProxy) startStreaming() {
+	w.Streamed = true
+	if contentType := w.Header().Get(directinvoke.ContentTypeHeader); contentType != "" {
+		w.stream.Header().Set("Content-Type", contentType)
+	}
+	if w.Header().Get(directinvoke.ErrorTypeHeader) != "" {
+		w.stream.Header().Set(functionErrorHeader, functionErrorUnhandled)
+	}
+	w.stream.Header().Del("Content-Length")
+
+	statusCode := w.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.stream.WriteHeader(statusCode)
+}
+
+func (w *ResponseWriterProxy) Flush() {
+	if flusher, ok := w.stream.(http.Flusher); ok && w.Streamed && !w.clientGone {
+		flusher.Flush()
+	}
 }
 
 func (w *ResponseWriterProxy) WriteHeader(statusCode int) {
@@ -42,3 +123,31 @@ func (w *ResponseWriterProxy) WriteHeader(statusCode int) {
 func (w *ResponseWriterProxy) IsError() bool {
 	return w.StatusCode != 0 && w.StatusCode/100 != 2
 }
+
+// writeJSONError writes an error body in the same shape Lambda uses for invoke errors
+func writeJSONError(w http.ResponseWriter, statusCode int, errorType string, errorMessage string) {
+	body, err := json.Marshal(model.ErrorResponse{ErrorType: errorType, ErrorMessage: errorMessage})
+	if err != nil {
+		log.Errorf("Failed to marshal error response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// writeJSON writes v as a JSON body with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("Failed to marshal response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
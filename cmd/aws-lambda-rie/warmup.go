@@ -0,0 +1,84 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.amzn.com/lambda/interop"
+)
+
+const (
+	warmupPayloadEnvKey  = "AWS_LAMBDA_RIE_WARMUP_PAYLOAD"
+	defaultWarmupPayload = "{}"
+	warmupCountParam     = "count"
+	// bounds a warmup so that a typo in the count does not hold the sandbox for hours
+	maxWarmupCount = 1000
+
+	invalidWarmupCountType = "InvalidWarmupCount"
+	warmupFailedType       = "WarmupFailed"
+)
+
+type warmupResponse struct {
+	Invokes    int   `json:"invokes"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// WarmupHandler performs ?count=N throwaway invokes, 1 by default, so that a benchmark starts with a runtime that
+// already compiled its hot paths and opened its connections. The payload is the request body, or
+// AWS_LAMBDA_RIE_WARMUP_PAYLOAD when it is empty. Like the readiness canary, the invokes bypass the router, so
+// they are neither recorded in the history nor counted in the state of the sandbox. The first failed invoke
+// stops the warmup.
+func WarmupHandler(w http.ResponseWriter, r *http.Request, sandbox Sandbox, bs interop.Bootstrap) {
+	count := 1
+	if value := r.URL.Query().Get(warmupCountParam); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxWarmupCount {
+			writeJSONError(w, http.StatusBadRequest, invalidWarmupCountType,
+				fmt.Sprintf("The warmup count must be between 1 and %d, got %q", maxWarmupCount, value))
+			return
+		}
+		count = parsed
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if err != nil {
+		writeInvokeAPIError(w, http.StatusRequestEntityTooLarge, payloadTooLargeMessage())
+		return
+	}
+	if len(payload) == 0 {
+		payload = []byte(GetenvWithDefault(warmupPayloadEnvKey, defaultWarmupPayload))
+	}
+
+	log.Infof("Warming up the function with %d invokes", count)
+	start := time.Now()
+	for i := 1; i <= count; i++ {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, invokePath, bytes.NewReader(payload))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, warmupFailedType, err.Error())
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := newBufferedResponse()
+		InvokeHandler(resp, req, sandbox, bs)
+		if resp.statusCode != http.StatusOK || resp.header.Get(functionErrorHeader) != "" {
+			log.Warnf("Warmup invoke %d of %d failed with status %d: %s", i, count, resp.statusCode, redactPayload(resp.body.Bytes()))
+			writeJSONError(w, http.StatusBadGateway, warmupFailedType,
+				fmt.Sprintf("Warmup invoke %d of %d failed with status %d: %s", i, count, resp.statusCode, resp.body.String()))
+			return
+		}
+		if r.Context().Err() != nil {
+			log.Infof("Warmup stopped after %d of %d invokes, the client disconnected", i, count)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, warmupResponse{Invokes: count, DurationMs: time.Since(start).Milliseconds()})
+}
@@ -0,0 +1,70 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.amzn.com/lambda/interop"
+)
+
+func warmup(t *testing.T, sandbox Sandbox, target string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	initDone = false
+	t.Cleanup(func() { initDone = false })
+
+	w := httptest.NewRecorder()
+	WarmupHandler(w, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)), sandbox, NewSimpleBootstrap([]string{}, ""))
+	return w
+}
+
+func TestWarmupHandler(t *testing.T) {
+	t.Setenv(warmupPayloadEnvKey, `{"warmup": true}`)
+	var payloads []string
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		body, _ := io.ReadAll(i.Payload)
+		payloads = append(payloads, string(body))
+		w.Write([]byte(`"ok"`))
+		return nil
+	}}
+
+	w := warmup(t, sandbox, "/_rie/warmup?count=3", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"invokes":3`)
+	assert.Equal(t, []string{`{"warmup": true}`, `{"warmup": true}`, `{"warmup": true}`}, payloads)
+	assert.Equal(t, 1, sandbox.initCalls, "the first warmup initializes the function")
+
+	payloads = nil
+	w = warmup(t, sandbox, "/_rie/warmup", `{"bench": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{`{"bench": 1}`}, payloads, "one invoke with the request body by default")
+
+	for _, count := range []string{"0", "-1", "many", "1001"} {
+		w = warmup(t, sandbox, "/_rie/warmup?count="+count, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, count)
+		assert.Contains(t, w.Body.String(), invalidWarmupCountType)
+	}
+}
+
+func TestWarmupStopsAtTheFirstFailure(t *testing.T) {
+	invokes := 0
+	sandbox := &mockSandbox{invoke: func(w http.ResponseWriter, i *interop.Invoke) error {
+		invokes++
+		if invokes == 2 {
+			return respondWithFunctionError(`{"errorType": "Exception"}`)(w, i)
+		}
+		return respondWith(`"ok"`)(w, i)
+	}}
+
+	w := warmup(t, sandbox, "/_rie/warmup?count=5", "")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), warmupFailedType)
+	assert.Contains(t, w.Body.String(), "Warmup invoke 2 of 5 failed")
+	assert.Equal(t, 2, invokes)
+}
@@ -27,6 +27,7 @@ type CredentialsService interface {
 	SetCredentials(token, awsKey, awsSecret, awsSession string, expiration time.Time)
 	GetCredentials(token string) (*Credentials, error)
 	UpdateCredentials(awsKey, awsSecret, awsSession string, expiration time.Time) error
+	Clear()
 }
 
 type credentialsServiceImpl struct {
@@ -87,3 +88,11 @@ func (c *credentialsServiceImpl) UpdateCredentials(awsKey, awsSecret, awsSession
 	c.SetCredentials(token, awsKey, awsSecret, awsSession, expiration)
 	return nil
 }
+
+// Clear removes the credentials of the previous init, whose token the runtime of the next init does not have
+func (c *credentialsServiceImpl) Clear() {
+	c.contentMutex.Lock()
+	defer c.contentMutex.Unlock()
+
+	c.credentials = make(map[string]Credentials)
+}
@@ -68,3 +68,19 @@ func TestUpdateCredentialsFail(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestUpdateCredentialsAfterClear(t *testing.T) {
+	credentialsService := NewCredentialsService()
+	credentialsExpiration := time.Now().Add(15 * time.Minute)
+
+	credentialsService.SetCredentials(Token, AwsKey, AwsSecret, AwsSession, credentialsExpiration)
+	credentialsService.Clear()
+	credentialsService.SetCredentials("nextToken", AwsKey, AwsSecret, AwsSession, credentialsExpiration)
+
+	assert.NoError(t, credentialsService.UpdateCredentials("updatedKey", AwsSecret, AwsSession, credentialsExpiration))
+	_, err := credentialsService.GetCredentials(Token)
+	assert.Error(t, err)
+	credentials, err := credentialsService.GetCredentials("nextToken")
+	assert.NoError(t, err)
+	assert.Equal(t, "updatedKey", credentials.AwsKey)
+}
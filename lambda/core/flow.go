@@ -146,6 +146,7 @@ type InvokeFlowSynchronization interface {
 	SetAgentsReadyCount(agentCount uint16) error
 	AgentReady() error
 	AwaitAgentsReady() error
+	AwaitAgentsReadyWithDeadline(context.Context) error
 	CancelWithError(error)
 	Clear()
 }
@@ -213,6 +214,23 @@ func (s *invokeFlowSynchronizationImpl) AwaitAgentsReady() error {
 	return s.agentReadyGate.AwaitGateCondition()
 }
 
+// AwaitAgentsReadyWithDeadline awaits for the extensions to report ready until ctx is done, then
+// cancels the invoke flow with interop.ErrAgentsReadyTimeout
+func (s *invokeFlowSynchronizationImpl) AwaitAgentsReadyWithDeadline(ctx context.Context) error {
+	errorChan := make(chan error, 1)
+	go func() {
+		errorChan <- s.agentReadyGate.AwaitGateCondition()
+	}()
+
+	select {
+	case err := <-errorChan:
+		return err
+	case <-ctx.Done():
+		s.CancelWithError(interop.ErrAgentsReadyTimeout)
+		return interop.ErrAgentsReadyTimeout
+	}
+}
+
 // NewInvokeFlowSynchronization returns new InvokeFlowSynchronization instance.
 func NewInvokeFlowSynchronization() InvokeFlowSynchronization {
 	return &invokeFlowSynchronizationImpl{
@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.amzn.com/lambda/interop"
+)
+
+func TestInvokeFlowAwaitsEveryExtension(t *testing.T) {
+	invokeFlow := NewInvokeFlowSynchronization()
+	require.NoError(t, invokeFlow.InitializeBarriers())
+	require.NoError(t, invokeFlow.SetAgentsReadyCount(2))
+
+	// the first of two extensions called next, the invoke is not done before the second one does
+	require.NoError(t, invokeFlow.AgentReady())
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		done <- invokeFlow.AwaitAgentsReadyWithDeadline(ctx)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("invoke flow done with one extension ready: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, invokeFlow.AgentReady())
+	assert.NoError(t, <-done)
+}
+
+func TestInvokeFlowExtensionsDeadline(t *testing.T) {
+	invokeFlow := NewInvokeFlowSynchronization()
+	require.NoError(t, invokeFlow.InitializeBarriers())
+	require.NoError(t, invokeFlow.SetAgentsReadyCount(2))
+	require.NoError(t, invokeFlow.AgentReady())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, interop.ErrAgentsReadyTimeout, invokeFlow.AwaitAgentsReadyWithDeadline(ctx))
+	// the flow is canceled, the runtime does not wait for the late extension either
+	assert.Equal(t, interop.ErrAgentsReadyTimeout, invokeFlow.AwaitRuntimeReady())
+
+	// the reset that follows the failed invoke clears the flow for the next one
+	invokeFlow.Clear()
+	require.NoError(t, invokeFlow.InitializeBarriers())
+	require.NoError(t, invokeFlow.SetAgentsReadyCount(1))
+	require.NoError(t, invokeFlow.AgentReady())
+	assert.NoError(t, invokeFlow.AwaitAgentsReadyWithDeadline(context.Background()))
+}
@@ -419,6 +419,9 @@ func (s *mockInvokeFlowSynchronization) SetAgentsReadyCount(agentCount uint16) e
 func (s *mockInvokeFlowSynchronization) AwaitAgentsReady() error {
 	return nil
 }
+func (s *mockInvokeFlowSynchronization) AwaitAgentsReadyWithDeadline(ctx context.Context) error {
+	return nil
+}
 func (s *mockInvokeFlowSynchronization) AgentReady() error {
 	return nil
 }
@@ -29,7 +29,11 @@ type InitStartData struct {
 }
 
 func (d *InitStartData) String() string {
-	return fmt.Sprintf("INIT START(type: %s, phase: %s)", d.InitializationType, d.Phase)
+	if d.RuntimeVersion == "" {
+		return fmt.Sprintf("INIT START(type: %s, phase: %s)", d.InitializationType, d.Phase)
+	}
+	return fmt.Sprintf("INIT START(type: %s, phase: %s, runtimeVersion: %s, runtimeVersionArn: %s)",
+		d.InitializationType, d.Phase, d.RuntimeVersion, d.RuntimeVersionArn)
 }
 
 type InitRuntimeDoneData struct {
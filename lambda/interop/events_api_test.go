@@ -654,3 +654,12 @@ func TestJsonMarshalReportWithInit(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, expected, string(actual))
 }
+
+func TestInitStartStringIncludesRuntimeVersion(t *testing.T) {
+	data := InitStartData{InitializationType: "on-demand", Phase: "init"}
+	assert.Equal(t, "INIT START(type: on-demand, phase: init)", data.String())
+
+	data.RuntimeVersion = "python:3.12"
+	data.RuntimeVersionArn = "arn:aws:lambda:us-east-1::runtime:abc"
+	assert.Equal(t, "INIT START(type: on-demand, phase: init, runtimeVersion: python:3.12, runtimeVersionArn: arn:aws:lambda:us-east-1::runtime:abc)", data.String())
+}
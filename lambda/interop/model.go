@@ -407,6 +407,10 @@ type InternalStateGetter func() statejson.InternalStateDescription
 // the timeout value.
 var ErrRestoreHookTimeout = errors.New("Runtime.RestoreHookUserTimeout")
 
+// ErrAgentsReadyTimeout is returned when the extensions did not call /extension/event/next
+// after an invoke before the extensions deadline
+var ErrAgentsReadyTimeout = errors.New("Extension.Timeout")
+
 // ErrRestoreHookUserError is returned as a response to `RESTORE` message
 // when function's restore hook faces with an error on throws an exception.
 // UserError contains the error type that the runtime encountered.
@@ -85,6 +85,11 @@ func NewServer(
 	}
 }
 
+// Use wraps the Runtime API handler with middleware, it must be called before Serve()
+func (s *Server) Use(middleware func(http.Handler) http.Handler) {
+	s.server.Handler = middleware(s.server.Handler)
+}
+
 // Listen on port
 func (s *Server) Listen() error {
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
@@ -71,6 +71,7 @@ type rapidContext struct {
 	standaloneMode           bool
 	eventsAPI                interop.EventsAPI
 	initCachingEnabled       bool
+	extensionsReadyTimeout   time.Duration
 	credentialsService       core.CredentialsService
 	handlerExecutionMutex    sync.Mutex
 	shutdownContext          *shutdownContext
@@ -512,7 +513,7 @@ func doInvoke(execCtx *rapidContext, invokeRequest *interop.Invoke, mx *invokeMe
 			execCtx.interopServer.SendRuntimeReady()
 			log.Debug("Await agents ready")
 			//TODO handle Supervisors listening channel
-			if err := invokeFlow.AwaitAgentsReady(); err != nil {
+			if err := execCtx.awaitAgentsReady(); err != nil {
 				log.Warnf("AwaitAgentsReady() = %s", err)
 				return err
 			}
@@ -531,6 +532,17 @@ func doInvoke(execCtx *rapidContext, invokeRequest *interop.Invoke, mx *invokeMe
 	}))
 }
 
+// awaitAgentsReady waits for the extensions subscribed to INVOKE to call next, for no longer than
+// the extensions ready timeout when one is set
+func (c *rapidContext) awaitAgentsReady() error {
+	if c.extensionsReadyTimeout <= 0 {
+		return c.invokeFlow.AwaitAgentsReady()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.extensionsReadyTimeout)
+	defer cancel()
+	return c.invokeFlow.AwaitAgentsReadyWithDeadline(ctx)
+}
+
 // acceptInitRequest is a second initialization phase, performed after receiving START
 // initialized entities: _HANDLER, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
 func (c *rapidContext) acceptInitRequest(initRequest *interop.Init) *interop.Init {
@@ -697,6 +709,7 @@ func reinitialize(execCtx *rapidContext) {
 	execCtx.renderingService.SetRenderer(nil)
 	execCtx.initDone = false
 	execCtx.registrationService.Clear()
+	execCtx.credentialsService.Clear()
 	execCtx.initFlow.Clear()
 	execCtx.invokeFlow.Clear()
 	if execCtx.telemetryAPIEnabled {
@@ -866,12 +879,7 @@ func handleRestore(execCtx *rapidContext, restore *interop.Restore) (interop.Res
 }
 
 func startRuntimeAPI(ctx context.Context, execCtx *rapidContext) {
-	// Start Runtime API Server
-	err := execCtx.server.Listen()
-	if err != nil {
-		log.WithError(err).Panic("Runtime API Server failed to listen")
-	}
-
+	// Start Runtime API Server, it is already listening (see Start)
 	execCtx.server.Serve(ctx) // blocking until server exits
 
 	// Note, most of initialization code should run before blocking to receive START,
@@ -8,7 +8,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
+	"time"
 
 	"go.amzn.com/lambda/appctx"
 	"go.amzn.com/lambda/core"
@@ -36,6 +38,8 @@ type Sandbox struct {
 	RuntimeFsRootPath        string // path to the root of the domain within the root mnt namespace. Reqired to find extensions
 	RuntimeAPIHost           string
 	RuntimeAPIPort           int
+	RuntimeAPIMiddleware     func(http.Handler) http.Handler
+	ExtensionsReadyTimeout   time.Duration
 }
 
 // Start pings Supervisor, and starts the Runtime API server. It allows the caller to configure:
@@ -52,7 +56,12 @@ type Sandbox struct {
 //
 // - Contexts & Data:
 //   - ctx is used to gracefully terminate Runtime API HTTP Server on exit
-func Start(ctx context.Context, s *Sandbox) (interop.RapidContext, interop.InternalStateGetter, string) {
+//
+// - RuntimeAPIMiddleware: optionally wraps the Runtime API handler, e.g. for fault injection in tests
+// - ExtensionsReadyTimeout: optionally bounds how long an invoke waits for the extensions to call next
+//
+// It returns an error when the Runtime API server cannot listen on its address.
+func Start(ctx context.Context, s *Sandbox) (interop.RapidContext, interop.InternalStateGetter, string, error) {
 	// Initialize internal state objects required by Rapid handlers
 	appCtx := appctx.NewApplicationContext()
 	initFlow := core.NewInitFlowSynchronization()
@@ -64,6 +73,16 @@ func Start(ctx context.Context, s *Sandbox) (interop.RapidContext, interop.Inter
 	appctx.StoreInitType(appCtx, s.InitCachingEnabled)
 
 	server := rapi.NewServer(s.RuntimeAPIHost, s.RuntimeAPIPort, appCtx, registrationService, renderingService, s.EnableTelemetryAPI, s.LogsSubscriptionAPI, s.TelemetrySubscriptionAPI, credentialsService)
+
+	if s.RuntimeAPIMiddleware != nil {
+		server.Use(s.RuntimeAPIMiddleware)
+	}
+
+	// Listen before returning so that a port conflict stops the sandbox at startup,
+	// instead of the runtime never reaching the Runtime API and the first invoke failing
+	if err := server.Listen(); err != nil {
+		return nil, nil, "", fmt.Errorf("the Runtime API server failed to listen on %s:%d, the port may be in use by another process: %w", s.RuntimeAPIHost, s.RuntimeAPIPort, err)
+	}
 	runtimeAPIAddr := fmt.Sprintf("%s:%d", server.Host(), server.Port())
 
 	// TODO: pass this directly down to HTTP servers and handlers, instead of using
@@ -93,6 +112,7 @@ func Start(ctx context.Context, s *Sandbox) (interop.RapidContext, interop.Inter
 		standaloneMode:           s.StandaloneMode,
 		eventsAPI:                s.EventsAPI,
 		initCachingEnabled:       s.InitCachingEnabled,
+		extensionsReadyTimeout:   s.ExtensionsReadyTimeout,
 		supervisor: processSupervisor{
 			ProcessSupervisor: s.Supervisor,
 			RootPath:          s.RuntimeFsRootPath,
@@ -105,7 +125,7 @@ func Start(ctx context.Context, s *Sandbox) (interop.RapidContext, interop.Inter
 
 	go startRuntimeAPI(ctx, execCtx)
 
-	return execCtx, registrationService.GetInternalStateDescriptor(appCtx), runtimeAPIAddr
+	return execCtx, registrationService.GetInternalStateDescriptor(appCtx), runtimeAPIAddr, nil
 }
 
 func (r *rapidContext) HandleInit(init *interop.Init, initSuccessResponseChan chan<- interop.InitSuccess, initFailureResponseChan chan<- interop.InitFailure) {
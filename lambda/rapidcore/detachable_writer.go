@@ -0,0 +1,68 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package rapidcore
+
+import (
+	"net/http"
+	"sync"
+)
+
+// detachableWriter is the reply stream of an invoke that is not reset when it times out. The runtime keeps
+// running the invoke, so its response can arrive after Invoke returned and must then no longer reach the caller.
+type detachableWriter struct {
+	mutex    sync.Mutex
+	w        http.ResponseWriter
+	detached bool
+	// spare receives the headers set once detached
+	spare http.Header
+}
+
+func newDetachableWriter(w http.ResponseWriter) *detachableWriter {
+	return &detachableWriter{w: w}
+}
+
+// detach discards what is written from now on
+func (d *detachableWriter) detach() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.detached = true
+}
+
+func (d *detachableWriter) Header() http.Header {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.detached {
+		if d.spare == nil {
+			d.spare = http.Header{}
+		}
+		return d.spare
+	}
+	return d.w.Header()
+}
+
+func (d *detachableWriter) Write(b []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.detached {
+		return len(b), nil
+	}
+	return d.w.Write(b)
+}
+
+func (d *detachableWriter) WriteHeader(statusCode int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.detached {
+		d.w.WriteHeader(statusCode)
+	}
+}
+
+// Flush keeps streamed responses working, NewStreamedResponseWriter requires an http.Flusher
+func (d *detachableWriter) Flush() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if flusher, ok := d.w.(http.Flusher); ok && !d.detached {
+		flusher.Flush()
+	}
+}
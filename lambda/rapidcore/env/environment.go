@@ -16,6 +16,7 @@ const handlerEnvKey = "_HANDLER"
 const executionEnvKey = "AWS_EXECUTION_ENV"
 const taskRootEnvKey = "LAMBDA_TASK_ROOT"
 const runtimeDirEnvKey = "LAMBDA_RUNTIME_DIR"
+const timezoneEnvKey = "TZ"
 
 // Environment holds env vars for runtime, agents, and for
 // internal use, parsed during startup and from START msg
@@ -86,6 +87,11 @@ func (e *Environment) SetTaskRoot(taskRoot string) {
 	e.runtime[taskRootEnvKey] = taskRoot
 }
 
+// SetTimezone sets the TZ environment variable for Runtime and agents
+func (e *Environment) SetTimezone(timezone string) {
+	e.platform[timezoneEnvKey] = timezone
+}
+
 // SetRuntimeDir sets the LAMBDA_RUNTIME_DIR environment variable for Runtime
 func (e *Environment) SetRuntimeDir(runtimeDir string) {
 	e.runtime[runtimeDirEnvKey] = runtimeDir
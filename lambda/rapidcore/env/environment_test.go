@@ -210,6 +210,19 @@ func TestRuntimeExecEnvironmentVariablesPriority(t *testing.T) {
 	assert.Contains(t, rapidEnvVars, conflictPlatformKeyFromCLI+"="+runtimeEnvVal)
 }
 
+func TestRuntimeAPIAddressCannotBeOverriddenByCustomer(t *testing.T) {
+	os.Clearenv()
+	os.Setenv(runtimeAPIAddressKey, "external:9001")
+	customerEnv := map[string]string{runtimeAPIAddressKey: "customer:9001"}
+
+	env := NewEnvironment()
+	env.StoreRuntimeAPIEnvironmentVariable("127.0.0.1:9001")
+	env.StoreEnvironmentVariablesFromInit(customerEnv, "", "", "", "", "", "")
+
+	assert.Equal(t, "127.0.0.1:9001", env.RuntimeExecEnv()[runtimeAPIAddressKey])
+	assert.Equal(t, "127.0.0.1:9001", env.AgentExecEnv()[runtimeAPIAddressKey])
+}
+
 func TestCustomerEnvironmentVariablesFromInitCanOverrideEnvironmentVariablesFromCLIOptions(t *testing.T) {
 	platformEnvVal, credsEnvVal, customerEnvVal := "platform", "creds", "customer"
 	lcisCLIArgEnvVal := "lcis"
@@ -3,11 +3,30 @@
 
 package rapidcore
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var ErrInitDoneFailed = errors.New("InitDoneFailed")
 var ErrInitNotStarted = errors.New("InitNotStarted")
 var ErrInitResetReceived = errors.New("InitResetReceived")
+var ErrInitTimeout = errors.New("InitTimeout")
+
+// InitTimeoutError is returned when init does not complete within the init timeout, it matches ErrInitTimeout
+type InitTimeoutError struct {
+	Timeout time.Duration
+	Elapsed time.Duration
+}
+
+func (e *InitTimeoutError) Error() string {
+	return fmt.Sprintf("%s: init did not complete within %s (elapsed %s)", ErrInitTimeout, e.Timeout, e.Elapsed.Round(time.Millisecond))
+}
+
+func (e *InitTimeoutError) Is(target error) bool {
+	return target == ErrInitTimeout
+}
 
 var ErrNotReserved = errors.New("NotReserved")
 var ErrAlreadyReserved = errors.New("AlreadyReserved")
@@ -7,10 +7,12 @@ import (
 	"context"
 	"io"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"go.amzn.com/lambda/extensions"
 	"go.amzn.com/lambda/interop"
@@ -33,6 +35,7 @@ type SandboxBuilder struct {
 	lambdaInvokeAPI        LambdaInvokeAPI
 	defaultInteropServer   *Server
 	useCustomInteropServer bool
+	drainFuncs             []func()
 	shutdownFuncs          []func()
 	handler                string
 }
@@ -149,6 +152,19 @@ func (b *SandboxBuilder) SetLogsEgressAPI(logsEgressAPI telemetry.StdLogsEgressA
 	return b
 }
 
+// SetRuntimeAPIMiddleware wraps every request the runtime and extensions make to the Runtime API
+func (b *SandboxBuilder) SetRuntimeAPIMiddleware(middleware func(http.Handler) http.Handler) *SandboxBuilder {
+	b.sandbox.RuntimeAPIMiddleware = middleware
+	return b
+}
+
+// SetExtensionsReadyTimeout bounds how long an invoke waits for the extensions to call next after it,
+// an invoke whose extensions are not ready in time fails and the sandbox is reset
+func (b *SandboxBuilder) SetExtensionsReadyTimeout(timeout time.Duration) *SandboxBuilder {
+	b.sandbox.ExtensionsReadyTimeout = timeout
+	return b
+}
+
 func (b *SandboxBuilder) SetHandler(handler string) *SandboxBuilder {
 	b.handler = handler
 	return b
@@ -159,20 +175,32 @@ func (b *SandboxBuilder) AddShutdownFunc(shutdownFunc func()) *SandboxBuilder {
 	return b
 }
 
-func (b *SandboxBuilder) Create() (interop.SandboxContext, interop.InternalStateGetter) {
+// AddDrainFunc registers a function that runs on SIGINT and SIGTERM before the shutdown
+// functions, while the sandbox can still serve the invokes in flight
+func (b *SandboxBuilder) AddDrainFunc(drainFunc func()) *SandboxBuilder {
+	b.drainFuncs = append(b.drainFuncs, drainFunc)
+	return b
+}
+
+// Create starts the sandbox, it fails when the Runtime API server cannot listen on its address
+func (b *SandboxBuilder) Create() (interop.SandboxContext, interop.InternalStateGetter, error) {
 	if !b.useCustomInteropServer {
 		b.sandbox.InteropServer = b.defaultInteropServer
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// cancel is called when handling termination signals as a cancellation
-	// signal to the Runtime API sever to terminate gracefully
-	go signalHandler(cancel, b.shutdownFuncs)
-
 	// rapid.Start, among other things, starts the Runtime API server and
 	// terminates it gracefully if the cxt is canceled
-	rapidCtx, internalStateFn, runtimeAPIAddr := rapid.Start(ctx, b.sandbox)
+	rapidCtx, internalStateFn, runtimeAPIAddr, err := rapid.Start(ctx, b.sandbox)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	// cancel is called when handling termination signals as a cancellation
+	// signal to the Runtime API sever to terminate gracefully
+	go signalHandler(cancel, append(b.drainFuncs, b.shutdownFuncs...))
 
 	b.sandboxContext = &SandboxContext{
 		rapidCtx:          rapidCtx,
@@ -180,7 +208,7 @@ func (b *SandboxBuilder) Create() (interop.SandboxContext, interop.InternalState
 		runtimeAPIAddress: runtimeAPIAddr,
 	}
 
-	return b.sandboxContext, internalStateFn
+	return b.sandboxContext, internalStateFn, nil
 }
 
 func (b *SandboxBuilder) DefaultInteropServer() *Server {
@@ -0,0 +1,26 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package rapidcore
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFailsWhenTheRuntimeAPIPortIsInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sandboxContext, internalStateFn, err := NewSandboxBuilder().SetRuntimeAPIAddress(listener.Addr().String()).Create()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), listener.Addr().String())
+	assert.Contains(t, err.Error(), "the port may be in use")
+	assert.Nil(t, sandboxContext)
+	assert.Nil(t, internalStateFn)
+}
@@ -39,6 +39,7 @@ func (l *EmulatorAPI) Init(i *interop.Init, timeoutMs int64) {
 		XRayDaemonAddress:            i.XRayDaemonAddress,
 		FunctionName:                 i.FunctionName,
 		FunctionVersion:              i.FunctionVersion,
+		InitTimeoutMs:                i.InitTimeoutMs,
 		CustomerEnvironmentVariables: i.CustomerEnvironmentVariables,
 		RuntimeInfo:                  i.RuntimeInfo,
 		SandboxType:                  i.SandboxType,
@@ -11,6 +11,8 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -89,6 +91,10 @@ type Server struct {
 	mutex         sync.Mutex
 	invokeCtx     *InvokeContext
 	invokeTimeout time.Duration
+	initTimeout   time.Duration
+	initStart     time.Time
+	// set with DisableTimeoutReset
+	timeoutResetDisabled bool
 
 	reservationContext context.Context
 	reservationCancel  func()
@@ -148,6 +154,48 @@ func (s *Server) GetInvokeTimeout() time.Duration {
 	return s.invokeTimeout
 }
 
+// DisableTimeoutReset keeps the sandbox when an invoke times out. The runtime then finishes the invoke on its own,
+// the sandbox is reserved until it does and what it responds is discarded. Init timeouts and failures still reset.
+func (s *Server) DisableTimeoutReset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.timeoutResetDisabled = true
+}
+
+func (s *Server) isTimeoutResetDisabled() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.timeoutResetDisabled
+}
+
+// SetInitTimeout bounds how long awaiting init waits, counted from the start of Init.
+// When it is not set, init gets the invoke timeout.
+func (s *Server) SetInitTimeout(timeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.initTimeout = timeout
+}
+
+func (s *Server) GetInitTimeout() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.initTimeout > 0 {
+		return s.initTimeout
+	}
+	return s.invokeTimeout
+}
+
+func (s *Server) getInitStart() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.initStart
+}
+
 func (s *Server) GetInvokeContext() *InvokeContext {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -308,6 +356,10 @@ func (s *Server) sendResponseUnsafe(invokeID string, additionalHeaders map[strin
 			log.Errorf("Failed to write response to %s: %s", invokeID, err)
 			reportedErr = err
 		}
+	} else if strings.EqualFold(additionalHeaders[directinvoke.FunctionResponseModeHeader], string(interop.FunctionResponseModeStreaming)) {
+		if err := s.streamResponseUnsafe(invokeID, additionalHeaders, payload, runtimeCalledResponse); err != nil {
+			return err
+		}
 	} else {
 		data, err := io.ReadAll(payload)
 		if err != nil {
@@ -322,6 +374,10 @@ func (s *Server) sendResponseUnsafe(invokeID string, additionalHeaders map[strin
 
 		startReadingResponseMonoTimeMs := metering.Monotime()
 		s.invokeCtx.ReplyStream.Header().Add(directinvoke.ContentTypeHeader, additionalHeaders[directinvoke.ContentTypeHeader])
+		if errorType, found := additionalHeaders[directinvoke.ErrorTypeHeader]; found && errorType != "" {
+			// lets the caller tell a function error apart from a successful response
+			s.invokeCtx.ReplyStream.Header().Set(directinvoke.ErrorTypeHeader, errorType)
+		}
 		written, err := s.invokeCtx.ReplyStream.Write(data)
 		if err != nil {
 			return fmt.Errorf("Failed to write response to %s: %s", invokeID, err)
@@ -348,6 +404,50 @@ func (s *Server) sendResponseUnsafe(invokeID string, additionalHeaders map[strin
 	return reportedErr
 }
 
+// streamResponseUnsafe forwards a streamed function response as it is produced, flushing every
+// chunk, so that the caller can answer with a chunked response instead of buffering the whole payload
+func (s *Server) streamResponseUnsafe(invokeID string, additionalHeaders map[string]string, payload io.Reader, runtimeCalledResponse bool) error {
+	startReadingResponseMonoTimeMs := metering.Monotime()
+	s.invokeCtx.ReplyStream.Header().Add(directinvoke.ContentTypeHeader, additionalHeaders[directinvoke.ContentTypeHeader])
+	s.invokeCtx.ReplyStream.Header().Set(directinvoke.FunctionResponseModeHeader, additionalHeaders[directinvoke.FunctionResponseModeHeader])
+	if errorType, found := additionalHeaders[directinvoke.ErrorTypeHeader]; found && errorType != "" {
+		s.invokeCtx.ReplyStream.Header().Set(directinvoke.ErrorTypeHeader, errorType)
+	}
+
+	flusher, canFlush := s.invokeCtx.ReplyStream.(http.Flusher)
+	buffer := make([]byte, 32*1024)
+	var written int64
+	for {
+		n, readErr := payload.Read(buffer)
+		if n > 0 {
+			if _, err := s.invokeCtx.ReplyStream.Write(buffer[:n]); err != nil {
+				return fmt.Errorf("Failed to write response to %s: %s", invokeID, err)
+			}
+			written += int64(n)
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("Failed to read response on %s: %s", invokeID, readErr)
+		}
+	}
+
+	s.sendResponseChan <- &interop.InvokeResponseMetrics{
+		ProducedBytes:                   written,
+		StartReadingResponseMonoTimeMs:  startReadingResponseMonoTimeMs,
+		FinishReadingResponseMonoTimeMs: metering.Monotime(),
+		TimeShapedNs:                    int64(-1),
+		OutboundThroughputBps:           int64(-1),
+		FunctionResponseMode:            interop.FunctionResponseModeStreaming,
+		RuntimeCalledResponse:           runtimeCalledResponse,
+	}
+	return nil
+}
+
 func (s *Server) SendResponse(invokeID string, resp *interop.StreamableInvokeResponse) error {
 	s.setRuntimeState(runtimeInvokeResponseSent)
 	s.mutex.Lock()
@@ -504,6 +604,7 @@ func (s *Server) setInitFailuresChan() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.initFailures = make(chan interop.InitFailure)
+	s.initStart = time.Now()
 }
 
 func (s *Server) getInitFailuresChan() chan interop.InitFailure {
@@ -514,6 +615,9 @@ func (s *Server) getInitFailuresChan() chan interop.InitFailure {
 
 func (s *Server) Init(i *interop.Init, invokeTimeoutMs int64) error {
 	s.SetInvokeTimeout(time.Duration(invokeTimeoutMs) * time.Millisecond)
+	if i.InitTimeoutMs > 0 {
+		s.SetInitTimeout(time.Duration(i.InitTimeoutMs) * time.Millisecond)
+	}
 	s.setRapidPhase(phaseInitializing)
 	s.setInitFailuresChan()
 	initCtx := s.sandboxContext.Init(i, invokeTimeoutMs)
@@ -624,14 +728,20 @@ func (s *Server) CurrentToken() *interop.Token {
 
 // Invoke is used by the Runtime Interface Emulator (Rapid Local)
 // https://github.com/aws/aws-lambda-runtime-interface-emulator
+// Invoke runs the invoke with the function timeout, or until its DeadlineNs when the caller set one
 func (s *Server) Invoke(responseWriter http.ResponseWriter, invoke *interop.Invoke) error {
 	resetCtx, resetCancel := context.WithCancel(context.Background())
 	defer resetCancel()
 
+	timeout := s.GetInvokeTimeout()
+	if deadlineNs, err := strconv.ParseInt(invoke.DeadlineNs, 10, 64); err == nil {
+		timeout = time.Duration(deadlineNs - metering.Monotime())
+	}
+
 	timeoutChan := make(chan error)
 	go func() {
 		select {
-		case <-time.After(s.GetInvokeTimeout()):
+		case <-time.After(timeout):
 			log.Debug("Invoke() timeout")
 			timeoutChan <- ErrInvokeTimeout
 		case <-resetCtx.Done():
@@ -644,8 +754,16 @@ func (s *Server) Invoke(responseWriter http.ResponseWriter, invoke *interop.Invo
 		return ErrInitNotStarted
 	}
 
-	releaseErrChan := make(chan error)
-	releaseSuccessChan := make(chan struct{})
+	var detachable *detachableWriter
+	if s.isTimeoutResetDisabled() {
+		detachable = newDetachableWriter(responseWriter)
+		responseWriter = detachable
+	}
+
+	// buffered, the release of an invoke that timed out without a reset comes after Invoke returned
+	releaseErrChan := make(chan error, 1)
+	releaseSuccessChan := make(chan struct{}, 1)
+	initTimeoutChan := make(chan error, 1)
 	go func() {
 		// This thread can block in one of two method calls Reserve() & AwaitRelease(),
 		// corresponding to Init and Invoke phase.
@@ -658,14 +776,26 @@ func (s *Server) Invoke(responseWriter http.ResponseWriter, invoke *interop.Invo
 		// The logic would be almost identical, except that init failures could manifest
 		// through return values of FastInvoke and not Reserve()
 
-		reserveResp, err := s.Reserve("", "", "")
+		// reserved with the ID of the invoke, so that the runtime and the events get the ID the caller knows
+		reserveResp, err := s.Reserve(invoke.ID, "", "")
+		if err == ErrAlreadyReserved {
+			// another invoke holds the sandbox, it must be neither awaited nor reset
+			releaseErrChan <- err
+			return
+		}
 		if err != nil {
 			log.Infof("ReserveFailed: %s", err)
 		}
 
-		invoke.DeadlineNs = fmt.Sprintf("%d", metering.Monotime()+reserveResp.Token.FunctionTimeout.Nanoseconds())
+		if invoke.DeadlineNs == "" {
+			invoke.DeadlineNs = fmt.Sprintf("%d", metering.Monotime()+reserveResp.Token.FunctionTimeout.Nanoseconds())
+		}
 		go func() {
 			if initCompletionResp, err := s.awaitInitialized(); err != nil {
+				if errors.Is(err, ErrInitTimeout) {
+					initTimeoutChan <- err
+					return
+				}
 				switch err {
 				case ErrInitResetReceived, ErrInitDoneFailed:
 					// For init failures, cache the response so they can be checked later
@@ -716,6 +846,11 @@ func (s *Server) Invoke(responseWriter http.ResponseWriter, invoke *interop.Invo
 	var err error
 	select {
 	case timeoutErr := <-timeoutChan:
+		if detachable != nil {
+			log.Warn("Invoke timed out, the sandbox is not reset and stays reserved until the runtime completes the invoke")
+			detachable.detach()
+			return timeoutErr
+		}
 		s.Reset(autoresetReasonTimeout, resetDefaultTimeoutMs)
 		select {
 		case releaseErr := <-releaseErrChan: // when AwaitRelease() has errors
@@ -723,6 +858,15 @@ func (s *Server) Invoke(responseWriter http.ResponseWriter, invoke *interop.Invo
 		case <-releaseSuccessChan: // when AwaitRelease() finishes cleanly
 		}
 		err = timeoutErr
+	case initTimeoutErr := <-initTimeoutChan:
+		log.Warn(initTimeoutErr)
+		s.Reset(autoresetReasonTimeout, resetDefaultTimeoutMs)
+		select {
+		case releaseErr := <-releaseErrChan:
+			log.Debugf("Invoke() release error on init timeout: %s", releaseErr)
+		case <-releaseSuccessChan:
+		}
+		err = initTimeoutErr
 	case err = <-releaseErrChan:
 		log.Debug("Invoke() release error")
 	case <-releaseSuccessChan:
@@ -738,9 +882,34 @@ type initCompletionResponse struct {
 	InitErrorMessage error
 }
 
+// awaitInitialized waits for init to complete, for no longer than the init timeout
 func (s *Server) awaitInitialized() (initCompletionResponse, error) {
-	initFailure, awaitingInitStatus := <-s.getInitFailuresChan()
 	resp := initCompletionResponse{}
+	initFailures := s.getInitFailuresChan()
+	if initFailures == nil {
+		return resp, ErrInitNotStarted
+	}
+
+	var initFailure interop.InitFailure
+	var awaitingInitStatus bool
+	select {
+	case initFailure, awaitingInitStatus = <-initFailures:
+	default:
+		timeout := s.GetInitTimeout()
+		if timeout <= 0 {
+			initFailure, awaitingInitStatus = <-initFailures
+			break
+		}
+
+		timer := time.NewTimer(timeout - time.Since(s.getInitStart()))
+		defer timer.Stop()
+
+		select {
+		case initFailure, awaitingInitStatus = <-initFailures:
+		case <-timer.C:
+			return resp, &InitTimeoutError{Timeout: timeout, Elapsed: time.Since(s.getInitStart())}
+		}
+	}
 
 	if initFailure.ResetReceived {
 		// Resets during Init are only received in standalone
@@ -9,12 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.amzn.com/lambda/core/statejson"
 	"go.amzn.com/lambda/interop"
+	"go.amzn.com/lambda/metering"
 	"go.amzn.com/lambda/rapidcore/env"
 )
 
@@ -542,3 +544,185 @@ func TestAwaitReleaseOnSuccess(t *testing.T) {
 See PlantUML state diagram for potential other uncovered paths
 through the state machine
 */
+
+func TestAwaitInitializedIsBoundedByInitTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+
+	releaseInit := make(chan struct{})
+	initHandler := func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+		<-releaseInit // deliberately slow init
+		sendInitSuccessResponse(successResp, interop.InitSuccess{})
+	}
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		initHandler,
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) { return interop.InvokeSuccess{}, nil },
+		func() (interop.ResetSuccess, *interop.ResetFailure) { return interop.ResetSuccess{}, nil },
+	}, "handler", "runtimeAPIhost:999"})
+	defer close(releaseInit)
+
+	srv.SetInitTimeout(50 * time.Millisecond)
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(1*time.Second*time.Millisecond))
+	_, err := srv.Reserve("", "", "")
+	require.NoError(t, err)
+
+	awaitInitErr := srv.AwaitInitialized()
+	require.True(t, errors.Is(awaitInitErr, ErrInitTimeout))
+
+	var initTimeoutErr *InitTimeoutError
+	require.True(t, errors.As(awaitInitErr, &initTimeoutErr))
+	require.Equal(t, 50*time.Millisecond, initTimeoutErr.Timeout)
+	require.GreaterOrEqual(t, initTimeoutErr.Elapsed, 50*time.Millisecond)
+	require.Contains(t, awaitInitErr.Error(), "InitTimeout")
+}
+
+func TestInitTimeoutIsTakenFromInit(t *testing.T) {
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+			sendInitSuccessResponse(successResp, interop.InitSuccess{})
+		},
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) { return interop.InvokeSuccess{}, nil },
+		func() (interop.ResetSuccess, *interop.ResetFailure) { return interop.ResetSuccess{}, nil },
+	}, "handler", "runtimeAPIhost:999"})
+
+	NewEmulatorAPI(srv).Init(&interop.Init{EnvironmentVariables: env.NewEnvironment(), InitTimeoutMs: 50}, int64(1*time.Second/time.Millisecond))
+	require.Equal(t, 50*time.Millisecond, srv.GetInitTimeout())
+	require.Equal(t, time.Second, srv.GetInvokeTimeout())
+}
+
+func TestAwaitInitializedWaitsForSlowInitWithinInitTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+
+	initHandler := func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+		time.Sleep(100 * time.Millisecond)
+		sendInitSuccessResponse(successResp, interop.InitSuccess{})
+	}
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		initHandler,
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) { return interop.InvokeSuccess{}, nil },
+		func() (interop.ResetSuccess, *interop.ResetFailure) { return interop.ResetSuccess{}, nil },
+	}, "handler", "runtimeAPIhost:999"})
+
+	srv.SetInitTimeout(2 * time.Second)
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(1*time.Second*time.Millisecond))
+	_, err := srv.Reserve("", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, srv.AwaitInitialized())
+	require.Equal(t, runtimeState(runtimeInitComplete), srv.getRuntimeState())
+}
+
+func TestInvokeWhileReservedReturnsAlreadyReserved(t *testing.T) {
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+			sendInitSuccessResponse(successResp, interop.InitSuccess{})
+		},
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) { return interop.InvokeSuccess{}, nil },
+		func() (interop.ResetSuccess, *interop.ResetFailure) { return interop.ResetSuccess{}, nil },
+	}, "handler", "runtimeAPIhost:999"})
+
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(1*time.Second*time.Millisecond))
+	_, err := srv.Reserve("", "", "") // an invoke in flight
+	require.NoError(t, err)
+
+	err = srv.Invoke(httptest.NewRecorder(), &interop.Invoke{ID: "concurrent"})
+	require.Equal(t, ErrAlreadyReserved, err)
+}
+
+func TestInvokeKeepsTheIDOfTheInvoke(t *testing.T) {
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+	var runtimeInvokeID string
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+			sendInitSuccessResponse(successResp, interop.InitSuccess{})
+		},
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) {
+			runtimeInvokeID = srv.GetCurrentInvokeID()
+			require.NoError(t, srv.SendResponse(runtimeInvokeID, &interop.StreamableInvokeResponse{Payload: bytes.NewReader([]byte("response"))}))
+			return interop.InvokeSuccess{}, nil
+		},
+		func() (interop.ResetSuccess, *interop.ResetFailure) { return interop.ResetSuccess{}, nil },
+	}, "handler", "runtimeAPIhost:999"})
+
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(1*time.Second*time.Millisecond))
+	invoke := &interop.Invoke{ID: "invoke-id"}
+	require.NoError(t, srv.Invoke(httptest.NewRecorder(), invoke))
+	require.Equal(t, "invoke-id", runtimeInvokeID)
+	require.Equal(t, "invoke-id", invoke.ID)
+}
+
+func TestInvokeTimesOutAtItsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+			sendInitSuccessResponse(successResp, interop.InitSuccess{})
+		},
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) {
+			<-release
+			return interop.InvokeSuccess{}, nil
+		},
+		func() (interop.ResetSuccess, *interop.ResetFailure) {
+			close(release)
+			return interop.ResetSuccess{}, nil
+		},
+	}, "handler", "runtimeAPIhost:999"})
+
+	// the function timeout is far longer than the invoke's own deadline
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(time.Minute/time.Millisecond))
+	deadline := metering.Monotime() + int64(50*time.Millisecond)
+
+	start := time.Now()
+	err := srv.Invoke(httptest.NewRecorder(), &interop.Invoke{ID: "short", DeadlineNs: strconv.FormatInt(deadline, 10)})
+
+	require.Equal(t, ErrInvokeTimeout, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestInvokeTimeoutWithoutReset(t *testing.T) {
+	release := make(chan struct{})
+	resets := 0
+	srv := NewServer()
+	srv.SetInternalStateGetter(func() statejson.InternalStateDescription { return statejson.InternalStateDescription{} })
+	srv.SetSandboxContext(&SandboxContext{&mockRapidCtx{
+		func(successResp chan<- interop.InitSuccess, failureResp chan<- interop.InitFailure) {
+			sendInitSuccessResponse(successResp, interop.InitSuccess{})
+		},
+		func() (interop.InvokeSuccess, *interop.InvokeFailure) {
+			<-release
+			response := &interop.StreamableInvokeResponse{Headers: map[string]string{}, Payload: bytes.NewReader([]byte("late"))}
+			require.NoError(t, srv.SendResponse(srv.GetCurrentInvokeID(), response))
+			require.NoError(t, srv.SendRuntimeReady())
+			return interop.InvokeSuccess{}, nil
+		},
+		func() (interop.ResetSuccess, *interop.ResetFailure) {
+			resets++
+			return interop.ResetSuccess{}, nil
+		},
+	}, "handler", "runtimeAPIhost:999"})
+	srv.DisableTimeoutReset()
+
+	srv.Init(&interop.Init{EnvironmentVariables: env.NewEnvironment()}, int64(time.Minute/time.Millisecond))
+	deadline := metering.Monotime() + int64(50*time.Millisecond)
+	timedOut := httptest.NewRecorder()
+	require.Equal(t, ErrInvokeTimeout, srv.Invoke(timedOut, &interop.Invoke{ID: "slow", DeadlineNs: strconv.FormatInt(deadline, 10)}))
+
+	// the runtime is still running the invoke that timed out
+	require.Equal(t, ErrAlreadyReserved, srv.Invoke(httptest.NewRecorder(), &interop.Invoke{ID: "next"}))
+
+	close(release)
+	require.Eventually(t, func() bool { return srv.CurrentToken() == nil }, time.Second, 10*time.Millisecond)
+	require.Empty(t, timedOut.Body.String(), "the late response does not reach the caller that timed out")
+
+	warm := httptest.NewRecorder()
+	require.NoError(t, srv.Invoke(warm, &interop.Invoke{ID: "warm"}))
+	require.Equal(t, "late", warm.Body.String())
+	require.Zero(t, resets)
+}
@@ -198,14 +198,23 @@ func (s *StandaloneEventsAPI) SendReportSpan(interop.Span) error {
 	return nil
 }
 
+// SendReport records a platform.report event with the record of the Telemetry API schema, see
+// https://docs.aws.amazon.com/lambda/latest/dg/telemetry-schema-reference.html#platform-report
 func (s *StandaloneEventsAPI) SendReport(data interop.ReportData) error {
+	requestID := data.RequestID
+	if requestID == "" {
+		requestID = s.requestID
+	}
 	record := map[string]interface{}{
-		"requestId": s.requestID,
+		"requestId": requestID,
 		"status":    data.Status,
 		"metrics":   data.Metrics,
-		"spans":     data.Spans,
-		"tracing":   data.Tracing,
 	}
+	// spans and tracing are optional in the schema, they are left out rather than null
+	if len(data.Spans) > 0 {
+		record["spans"] = data.Spans
+	}
+	s.addTracingToRecord(data.Tracing, record)
 	if data.ErrorType != nil {
 		record["errorType"] = data.ErrorType
 	}
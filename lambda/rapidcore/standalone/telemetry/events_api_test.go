@@ -0,0 +1,66 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package telemetry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.amzn.com/lambda/interop"
+)
+
+func TestSendReportMatchesTelemetrySchema(t *testing.T) {
+	eventsAPI := &StandaloneEventsAPI{}
+	eventsAPI.SetCurrentRequestID("6d68ca91-49c9-448d-89b8-7ca3e6dc66aa")
+
+	require.NoError(t, eventsAPI.SendReport(interop.ReportData{
+		Status: "success",
+		Metrics: interop.ReportMetrics{
+			DurationMs:       101.51,
+			BilledDurationMs: 102,
+			MemorySizeMB:     128,
+			MaxMemoryUsedMB:  64,
+			InitDurationMs:   80.25,
+		},
+	}))
+
+	events := eventsAPI.EventLog().Events
+	require.Len(t, events, 1)
+	assert.Equal(t, PlatformReport, events[0].Type)
+
+	record, err := json.Marshal(events[0].PlatformEvent)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"requestId": "6d68ca91-49c9-448d-89b8-7ca3e6dc66aa",
+		"status": "success",
+		"metrics": {
+			"durationMs": 101.51,
+			"billedDurationMs": 102,
+			"memorySizeMB": 128,
+			"maxMemoryUsedMB": 64,
+			"initDurationMs": 80.25
+		}
+	}`, string(record))
+}
+
+func TestSendReportIncludesTracingAndErrorType(t *testing.T) {
+	eventsAPI := &StandaloneEventsAPI{}
+	errorType := "Runtime.ExitError"
+
+	require.NoError(t, eventsAPI.SendReport(interop.ReportData{
+		RequestID: "request-id",
+		Status:    "error",
+		ErrorType: &errorType,
+		Tracing:   &interop.TracingCtx{SpanID: "span", Type: "X-Amzn-Trace-Id", Value: "Root=1-5e1b4151-5ac6c58f5902856f7f7b0ad5"},
+	}))
+
+	record := eventsAPI.EventLog().Events[0].PlatformEvent
+	assert.Equal(t, interop.RequestID("request-id"), record["requestId"])
+	assert.Equal(t, &errorType, record["errorType"])
+	assert.Equal(t, map[string]string{"spanId": "span", "type": "X-Amzn-Trace-Id", "value": "Root=1-5e1b4151-5ac6c58f5902856f7f7b0ad5"}, record["tracing"])
+	assert.NotContains(t, record, "spans")
+}
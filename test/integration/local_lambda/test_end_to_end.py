@@ -77,7 +77,7 @@ class TestEndToEnd(TestCase):
     def test_env_var_with_equal_sign(self, arch, port):
         image, rie, image_name = self.tagged_name("envvarcheck", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_env_var_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_env_var_handler"
         Popen(cmd.split(" ")).communicate()
 
         # sleep 1s to give enough time for the endpoint to be up to curl
@@ -92,7 +92,7 @@ class TestEndToEnd(TestCase):
     def test_two_invokes(self, arch, port):
         image, rie, image_name = self.tagged_name("twoinvokes", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.success_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.success_handler"
 
         Popen(cmd.split(" ")).communicate()
 
@@ -114,7 +114,7 @@ class TestEndToEnd(TestCase):
     def test_lambda_function_arn_exists(self, arch, port):
         image, rie, image_name = self.tagged_name("arnexists", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_lambda_arn_in_context"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_lambda_arn_in_context"
 
         Popen(cmd.split(" ")).communicate()
 
@@ -130,7 +130,7 @@ class TestEndToEnd(TestCase):
     def test_lambda_function_arn_exists_with_defining_custom_name(self, arch, port):
         image, rie, image_name = self.tagged_name("customname", arch)
 
-        cmd = f"docker run --name {image} --env AWS_LAMBDA_FUNCTION_NAME=MyCoolName -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_lambda_arn_in_context"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true --env AWS_LAMBDA_FUNCTION_NAME=MyCoolName -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_lambda_arn_in_context"
         Popen(cmd.split(" ")).communicate()
 
         # sleep 1s to give enough time for the endpoint to be up to curl
@@ -145,7 +145,7 @@ class TestEndToEnd(TestCase):
     def test_timeout_invoke(self, arch, port):
         image, rie, image_name = self.tagged_name("timeout", arch)
 
-        cmd = f"docker run --name {image} -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=1 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.sleep_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=1 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.sleep_handler"
 
         Popen(cmd.split(" ")).communicate()
 
@@ -155,13 +155,17 @@ class TestEndToEnd(TestCase):
         r = requests.post(
             f"http://localhost:{port}/2015-03-31/functions/function/invocations", json={}
         )
-        self.assertEqual(b"Task timed out after 1.00 seconds", r.content)
+        self.assertEqual(
+            b'{"errorMessage":"Task timed out after 1.00 seconds","errorType":"Sandbox.Timedout"}',
+            r.content,
+        )
+        self.assertEqual("Unhandled", r.headers["X-Amz-Function-Error"])
 
     @parameterized.expand([("x86_64", "8005"), ("arm64", "9005"), ("", "9055")])
     def test_exception_returned(self, arch, port):
         image, rie, image_name = self.tagged_name("exception", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.exception_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.exception_handler"
 
         Popen(cmd.split(" ")).communicate()
 
@@ -175,12 +179,14 @@ class TestEndToEnd(TestCase):
             b'{"errorMessage": "Raising an exception", "errorType": "Exception", "stackTrace": ["  File \\"/var/task/main.py\\", line 13, in exception_handler\\n    raise Exception(\\"Raising an exception\\")\\n"]}',
             r.content,
         )
+        self.assertEqual(200, r.status_code)
+        self.assertEqual("Unhandled", r.headers["X-Amz-Function-Error"])
 
     @parameterized.expand([("x86_64", "8006"), ("arm64", "9006"), ("", "9056")])
     def test_context_get_remaining_time_in_three_seconds(self, arch, port):
         image, rie, image_name = self.tagged_name("remaining_time_in_three_seconds", arch)
 
-        cmd = f"docker run --name {image} -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=3 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=3 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
 
         Popen(cmd.split(' ')).communicate()
 
@@ -199,7 +205,7 @@ class TestEndToEnd(TestCase):
     def test_context_get_remaining_time_in_ten_seconds(self, arch, port):
         image, rie, image_name = self.tagged_name("remaining_time_in_ten_seconds", arch)
 
-        cmd = f"docker run --name {image} -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=10 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d --env AWS_LAMBDA_FUNCTION_TIMEOUT=10 -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
 
         Popen(cmd.split(' ')).communicate()
 
@@ -218,7 +224,7 @@ class TestEndToEnd(TestCase):
     def test_context_get_remaining_time_in_default_deadline(self, arch, port):
         image, rie, image_name = self.tagged_name("remaining_time_in_default_deadline", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.check_remaining_time_handler"
 
         Popen(cmd.split(' ')).communicate()
 
@@ -237,7 +243,7 @@ class TestEndToEnd(TestCase):
     def test_invoke_with_pre_runtime_api_runtime(self, arch, port):
         image, rie, image_name = self.tagged_name("pre-runtime-api", arch)
 
-        cmd = f"docker run --name {image} -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.success_handler"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.success_handler"
 
         Popen(cmd.split(" ")).communicate()
 
@@ -253,7 +259,7 @@ class TestEndToEnd(TestCase):
     def test_function_name_is_overriden(self, arch, port):
         image, rie, image_name = self.tagged_name("assert-overwritten", arch)
 
-        cmd = f"docker run --name {image} -d --env AWS_LAMBDA_FUNCTION_NAME=MyCoolName -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_env_var_is_overwritten"
+        cmd = f"docker run --name {image} --env AWS_LAMBDA_RIE_ALLOW_REMOTE=true -d --env AWS_LAMBDA_FUNCTION_NAME=MyCoolName -v {self.path_to_binary}:/local-lambda-runtime-server -p {port}:8080 --entrypoint /local-lambda-runtime-server/{rie} {image_name} {DEFAULT_1P_ENTRYPOINT} main.assert_env_var_is_overwritten"
 
         Popen(cmd.split(" ")).communicate()
 